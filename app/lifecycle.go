@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hook is a component started and stopped with the application. Either
+// function may be nil.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle starts hooks in the order they were appended and stops them in
+// reverse order. Only hooks that started are stopped.
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+	errs    chan error
+}
+
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{
+		errs: make(chan error, 1),
+	}
+}
+
+func (lifecycle *Lifecycle) Append(hook Hook) {
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+
+	lifecycle.hooks = append(lifecycle.hooks, hook)
+}
+
+// Start runs the OnStart hooks. If one fails, the hooks already started are
+// stopped and the error is returned.
+func (lifecycle *Lifecycle) Start(ctx context.Context) error {
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+
+	for lifecycle.started < len(lifecycle.hooks) {
+		hook := lifecycle.hooks[lifecycle.started]
+		if hook.OnStart != nil {
+			err := hook.OnStart(ctx)
+			if err != nil {
+				err = fmt.Errorf("start %s: %w", hook.Name, err)
+				if stopErr := lifecycle.stop(ctx); stopErr != nil {
+					err = fmt.Errorf("%w; %v", err, stopErr)
+				}
+				return err
+			}
+		}
+		lifecycle.started++
+	}
+	return nil
+}
+
+// Stop runs the OnStop hooks of the started hooks, all of them even if some
+// fail, and returns their errors combined.
+func (lifecycle *Lifecycle) Stop(ctx context.Context) error {
+	lifecycle.mu.Lock()
+	defer lifecycle.mu.Unlock()
+
+	return lifecycle.stop(ctx)
+}
+
+func (lifecycle *Lifecycle) stop(ctx context.Context) error {
+	var errs []string
+	for ; lifecycle.started > 0; lifecycle.started-- {
+		hook := lifecycle.hooks[lifecycle.started-1]
+		if hook.OnStop == nil {
+			continue
+		}
+		err := hook.OnStop(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("stop %s: %v", hook.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Fail reports an error from a running component, such as a server that
+// stopped serving, and makes Run stop the application.
+func (lifecycle *Lifecycle) Fail(err error) {
+	select {
+	case lifecycle.errs <- err:
+	default:
+	}
+}
+
+// Run starts the application, waits until ctx is done or a component fails
+// and then stops it, allowing the hooks stopTimeout to finish.
+func (lifecycle *Lifecycle) Run(ctx context.Context, stopTimeout time.Duration) error {
+	err := lifecycle.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case runErr = <-lifecycle.errs:
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	err = lifecycle.Stop(stopCtx)
+	if runErr != nil {
+		return runErr
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/openapi"
+)
+
+const apiBasePath = "/api"
+
+var errorResponses = []struct {
+	status      int
+	name        string
+	description string
+}{
+	{http.StatusBadRequest, "BadRequest", "The request is invalid"},
+	{http.StatusUnauthorized, "Unauthorized", "The credential is missing or invalid"},
+	{http.StatusForbidden, "Forbidden", "The credential lacks the required scope"},
+	{http.StatusNotFound, "NotFound", "The category does not exist"},
+	{http.StatusConflict, "Conflict", "The request conflicts with existing categories"},
+	{http.StatusPreconditionFailed, "PreconditionFailed", "If-Match does not match the current ETag of the category"},
+	{http.StatusPreconditionRequired, "PreconditionRequired", "The If-Match header is required"},
+	{http.StatusTooManyRequests, "TooManyRequests", "The client exceeded its rate limit"},
+	{http.StatusInternalServerError, "InternalServerError", "The server failed"},
+}
+
+// Every route can fail with these.
+var commonErrors = []int{
+	http.StatusBadRequest,
+	http.StatusUnauthorized,
+	http.StatusForbidden,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+}
+
+// NewOpenAPI documents Routes. openapi/apispec.json is generated from it
+// with go generate ./openapi.
+func NewOpenAPI() *openapi.Document {
+	g := openapi.NewGenerator(openapi.Info{
+		Title:       "Category RESTful API",
+		Description: "API Specification for Category RESTful API",
+		Version:     "1.0",
+	}, apiBasePath)
+
+	components := &g.Document.Components
+	components.SecuritySchemes["CategoryAuth"] = &openapi.SecurityScheme{
+		Type: "apiKey", In: "header", Name: "X-API-KEY",
+		Description: "API key created with the apikey command",
+	}
+	components.SecuritySchemes["BearerAuth"] = &openapi.SecurityScheme{
+		Type: "http", Scheme: "bearer", BearerFormat: "JWT",
+		Description: "JWT with the required scopes in its scope or scp claim",
+	}
+	errorSchema := g.ResponseSchema(webresponse.WebResponse{})
+	problemSchema := g.ResponseSchema(webresponse.ProblemResponse{})
+	errorNames := map[int]string{}
+	for _, e := range errorResponses {
+		errorNames[e.status] = e.name
+		components.Responses[e.name] = &openapi.Response{
+			Description: e.description,
+			Content: map[string]*openapi.MediaType{
+				"application/json":         {Schema: errorSchema},
+				"application/problem+json": {Schema: problemSchema},
+			},
+		}
+	}
+
+	for _, route := range Routes {
+		operation := &openapi.Operation{
+			Tags:        []string{"Category API"},
+			Summary:     route.Summary,
+			Description: "Requires the " + route.Scope + " scope.",
+			Security:    []map[string][]string{{"CategoryAuth": {}}, {"BearerAuth": {}}},
+			Responses:   map[string]*openapi.Response{},
+		}
+
+		var segments []string
+		for _, segment := range strings.Split(strings.TrimPrefix(route.Path, apiBasePath), "/") {
+			if strings.HasPrefix(segment, ":") {
+				name := segment[1:]
+				operation.Parameters = append(operation.Parameters, pathParameter(name))
+				segment = "{" + name + "}"
+			}
+			segments = append(segments, segment)
+		}
+		if route.Query != nil {
+			operation.Parameters = append(operation.Parameters, g.QueryParameters(route.Query)...)
+		}
+		if route.Body != nil {
+			operation.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]*openapi.MediaType{"application/json": {Schema: g.RequestSchema(route.Body)}},
+			}
+		}
+
+		envelope := g.InlineResponseSchema(webresponse.WebResponse{})
+		envelope.Properties["data"] = &openapi.Schema{Nullable: true}
+		if route.Data != nil {
+			envelope.Properties["data"] = g.ResponseSchema(route.Data)
+		}
+		delete(envelope.Properties, "meta")
+		if len(route.Meta) > 0 {
+			meta := &openapi.Schema{}
+			for _, m := range route.Meta {
+				meta.AnyOf = append(meta.AnyOf, g.ResponseSchema(m))
+			}
+			envelope.Properties["meta"] = meta
+		}
+		operation.Responses["200"] = &openapi.Response{
+			Description: "OK",
+			Content:     map[string]*openapi.MediaType{"application/json": {Schema: envelope}},
+		}
+		if route.Conditional {
+			conditional(operation, route.Method)
+		}
+		for _, status := range append(append([]int{}, commonErrors...), route.Errors...) {
+			operation.Responses[strconv.Itoa(status)] = &openapi.Response{Ref: "#/components/responses/" + errorNames[status]}
+		}
+
+		g.AddOperation(route.Method, strings.Join(segments, "/"), operation)
+	}
+	return g.Document
+}
+
+// pathParameter documents the ids in the paths as positive integers.
+func pathParameter(name string) *openapi.Parameter {
+	minimum := 1.0
+	return &openapi.Parameter{
+		Name:        name,
+		In:          "path",
+		Required:    true,
+		Description: "Category id",
+		Schema:      &openapi.Schema{Type: "integer", Minimum: &minimum},
+	}
+}
+
+// conditional documents the ETag a GET returns and the precondition headers
+// that GET, PUT and DELETE accept.
+func conditional(operation *openapi.Operation, method string) {
+	parameter := &openapi.Parameter{
+		Name:        "If-Match",
+		In:          "header",
+		Description: "ETag of the version to change, or * for any; required unless concurrency.require_if_match is disabled",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+	if method == http.MethodGet {
+		parameter = &openapi.Parameter{
+			Name:        "If-None-Match",
+			In:          "header",
+			Description: "ETags the client already has",
+			Schema:      &openapi.Schema{Type: "string"},
+		}
+		operation.Responses["304"] = &openapi.Response{
+			Description: "The category still has one of the If-None-Match ETags",
+			Headers:     map[string]*openapi.Header{"ETag": etagHeader()},
+		}
+	}
+	operation.Parameters = append(operation.Parameters, parameter)
+	if method != http.MethodDelete {
+		operation.Responses["200"].Headers = map[string]*openapi.Header{"ETag": etagHeader()}
+	}
+}
+
+func etagHeader() *openapi.Header {
+	return &openapi.Header{Description: "Version of the category", Schema: &openapi.Schema{Type: "string"}}
+}
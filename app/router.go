@@ -1,19 +1,139 @@
 package app
 
 import (
+	"net/http"
+
 	"github.com/julienschmidt/httprouter"
+	"github.com/rtanx/golang-restful-api/auth"
 	"github.com/rtanx/golang-restful-api/controller"
 	"github.com/rtanx/golang-restful-api/exception"
+	"github.com/rtanx/golang-restful-api/metrics"
+	"github.com/rtanx/golang-restful-api/middleware"
+	webrequest "github.com/rtanx/golang-restful-api/model/web/request"
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/tracing"
 )
 
-func NewRouter(categoryController controller.CategoryController) *httprouter.Router {
-	router := httprouter.New()
+// Route is an API route: the scope a credential needs to call it, the rate
+// limit group it counts against, and what NewOpenAPI documents about it.
+// Query, Body, Data and Meta are zero values of the types the route reads
+// from the query string and body and writes in the WebResponse envelope.
+// Conditional routes use the ETag of the category: GET honours
+// If-None-Match, the other methods If-Match.
+type Route struct {
+	Method      string
+	Path        string
+	Scope       string
+	Group       string
+	Handle      func(controller.CategoryController, http.ResponseWriter, *http.Request, httprouter.Params)
+	Summary     string
+	Query       interface{}
+	Body        interface{}
+	Data        interface{}
+	Meta        []interface{}
+	Errors      []int
+	Conditional bool
+}
+
+var listMeta = []interface{}{webresponse.PageMeta{}, webresponse.CursorMeta{}}
 
-	router.GET("/api/categories", categoryController.FindAll)
-	router.POST("/api/categories", categoryController.Create)
-	router.GET("/api/categories/:categoryId", categoryController.FindById)
-	router.PUT("/api/categories/:categoryId", categoryController.Update)
-	router.DELETE("/api/categories/:categoryId", categoryController.Delete)
+var Routes = []Route{
+	{
+		Method: http.MethodGet, Path: "/api/categories",
+		Scope: auth.ScopeCategoriesRead, Group: "read",
+		Handle:  controller.CategoryController.FindAll,
+		Summary: "List categories",
+		Query:   webrequest.CategoryListRequest{},
+		Data:    []webresponse.CategoryResponse{},
+		Meta:    listMeta,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/categories",
+		Scope: auth.ScopeCategoriesWrite, Group: "write",
+		Handle:  controller.CategoryController.Create,
+		Summary: "Create a category",
+		Body:    webrequest.CategoryCreateRequest{},
+		Data:    webresponse.CategoryResponse{},
+		Errors:  []int{http.StatusConflict},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/categories/:categoryId",
+		Scope: auth.ScopeCategoriesRead, Group: "read",
+		Handle:      controller.CategoryController.FindById,
+		Summary:     "Get a category by id",
+		Data:        webresponse.CategoryResponse{},
+		Errors:      []int{http.StatusNotFound},
+		Conditional: true,
+	},
+	{
+		Method: http.MethodPut, Path: "/api/categories/:categoryId",
+		Scope: auth.ScopeCategoriesWrite, Group: "write",
+		Handle:      controller.CategoryController.Update,
+		Summary:     "Update a category by id",
+		Body:        webrequest.CategoryUpdateRequest{},
+		Data:        webresponse.CategoryResponse{},
+		Errors:      []int{http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired},
+		Conditional: true,
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/categories/:categoryId",
+		Scope: auth.ScopeCategoriesDelete, Group: "write",
+		Handle:      controller.CategoryController.Delete,
+		Summary:     "Delete a category by id",
+		Errors:      []int{http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired},
+		Conditional: true,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/categories/:categoryId/move",
+		Scope: auth.ScopeCategoriesWrite, Group: "write",
+		Handle:      controller.CategoryController.Move,
+		Summary:     "Move a category under another parent",
+		Body:        webrequest.CategoryMoveRequest{},
+		Data:        webresponse.CategoryResponse{},
+		Errors:      []int{http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired},
+		Conditional: true,
+	},
+	{
+		Method: http.MethodGet, Path: "/api/categories/:categoryId/children",
+		Scope: auth.ScopeCategoriesRead, Group: "read",
+		Handle:  controller.CategoryController.FindChildren,
+		Summary: "List the children of a category",
+		Query:   webrequest.CategoryListRequest{},
+		Data:    []webresponse.CategoryResponse{},
+		Meta:    listMeta,
+		Errors:  []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/categories/:categoryId/ancestors",
+		Scope: auth.ScopeCategoriesRead, Group: "read",
+		Handle:  controller.CategoryController.FindAncestors,
+		Summary: "List the ancestors of a category, root first",
+		Data:    []webresponse.CategoryResponse{},
+		Errors:  []int{http.StatusNotFound},
+	},
+	{
+		Method: http.MethodGet, Path: "/api/categories/:categoryId/subtree",
+		Scope: auth.ScopeCategoriesRead, Group: "read",
+		Handle:  controller.CategoryController.FindSubtree,
+		Summary: "Get a category and all its descendants",
+		Data:    webresponse.CategoryTreeResponse{},
+		Errors:  []int{http.StatusNotFound},
+	},
+}
+
+// NewRouter registers Routes, each behind its scope and rate limit group and
+// labelled with its pattern for metrics and tracing. A nil limiter disables
+// rate limiting.
+func NewRouter(categoryController controller.CategoryController, limiter *middleware.RateLimiter) *httprouter.Router {
+	router := httprouter.New()
+	for _, route := range Routes {
+		handle := route.Handle
+		var h httprouter.Handle = func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+			handle(categoryController, writer, request, params)
+		}
+		h = limiter.Limit(route.Group, middleware.RequireScope(route.Scope, h))
+		router.Handle(route.Method, route.Path, metrics.Route(route.Path, tracing.Route(route.Path, h)))
+	}
 
 	router.PanicHandler = exception.ErrorHandler
 
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/health"
+)
+
+func NewServer(serverConfig config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", serverConfig.Host, serverConfig.Port),
+		Handler:           handler,
+		ReadTimeout:       time.Duration(serverConfig.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(serverConfig.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(serverConfig.WriteTimeout),
+		IdleTimeout:       time.Duration(serverConfig.IdleTimeout),
+	}
+}
+
+// ServerHook listens on server.Addr when started, so that a port already in
+// use fails the start, and serves in the background. Stopping it stops
+// accepting connections and waits for in-flight requests until the stop
+// context is done.
+func ServerHook(server *http.Server, lifecycle *Lifecycle) Hook {
+	return Hook{
+		Name: "http server",
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			server.Addr = listener.Addr().String()
+			log.Printf("Starting Application on %s", server.Addr)
+
+			go func() {
+				err := server.Serve(listener)
+				if !errors.Is(err, http.ErrServerClosed) {
+					lifecycle.Fail(err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			log.Printf("Shutting down, draining in-flight requests")
+			return server.Shutdown(ctx)
+		},
+	}
+}
+
+// ReadinessHook makes /readyz report shutdown when stopped and then waits
+// delay, so that probes see it and load balancers stop routing here before
+// the server hook, appended before it, closes the listeners.
+func ReadinessHook(checker *health.Checker, delay time.Duration) Hook {
+	return Hook{
+		Name: "readiness",
+		OnStop: func(ctx context.Context) error {
+			checker.ShuttingDown()
+			if delay <= 0 {
+				return nil
+			}
+			log.Printf("Not ready, waiting %s before draining", delay)
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C:
+				return nil
+			}
+		},
+	}
+}
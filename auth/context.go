@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/model/domain"
+)
+
+type keyContextKey struct{}
+
+type claimsContextKey struct{}
+
+type signerContextKey struct{}
+
+func NewKeyContext(ctx context.Context, key domain.APIKey) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// KeyFromContext returns the API key the AuthMiddleware authenticated the
+// request with.
+func KeyFromContext(ctx context.Context) (domain.APIKey, bool) {
+	key, ok := ctx.Value(keyContextKey{}).(domain.APIKey)
+	return key, ok
+}
+
+func NewClaimsContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the bearer token the
+// AuthMiddleware authenticated the request with.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(Claims)
+	return claims, ok
+}
+
+func NewSignerContext(ctx context.Context, key config.HMACKey) context.Context {
+	return context.WithValue(ctx, signerContextKey{}, key)
+}
+
+// SignerFromContext returns the key that signed the request the
+// AuthMiddleware authenticated.
+func SignerFromContext(ctx context.Context) (config.HMACKey, bool) {
+	key, ok := ctx.Value(signerContextKey{}).(config.HMACKey)
+	return key, ok
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/protocol"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// HMACVerifier checks request signatures made with the shared secrets in
+// Keys. A signature is accepted once, and only within MaxSkew of its
+// timestamp.
+type HMACVerifier struct {
+	Keys    map[string]config.HMACKey
+	MaxSkew time.Duration
+	Nonces  *NonceCache
+}
+
+func NewHMACVerifier(hmacConfig config.HMACConfig) *HMACVerifier {
+	verifier := &HMACVerifier{
+		Keys:    map[string]config.HMACKey{},
+		MaxSkew: time.Duration(hmacConfig.MaxSkew),
+		Nonces:  NewNonceCache(),
+	}
+	for _, key := range hmacConfig.Keys {
+		verifier.Keys[key.Id] = key
+	}
+	return verifier
+}
+
+// Verify returns the key that signed r, whose body has already been read
+// into body.
+func (verifier *HMACVerifier) Verify(r *http.Request, body []byte, now time.Time) (config.HMACKey, error) {
+	key, ok := verifier.Keys[r.Header.Get(protocol.SignatureKeyIdHeader)]
+	if !ok {
+		return config.HMACKey{}, invalidSignature("unknown key")
+	}
+
+	timestamp := r.Header.Get(protocol.SignatureTimestampHeader)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return config.HMACKey{}, invalidSignature("malformed timestamp")
+	}
+	signedAt := time.Unix(seconds, 0)
+	if signedAt.Before(now.Add(-verifier.MaxSkew)) || signedAt.After(now.Add(verifier.MaxSkew)) {
+		return config.HMACKey{}, invalidSignature("stale timestamp")
+	}
+
+	nonce := r.Header.Get(protocol.SignatureNonceHeader)
+	if nonce == "" {
+		return config.HMACKey{}, invalidSignature("missing nonce")
+	}
+
+	expected := protocol.Sign(key.Secret, protocol.CanonicalRequest(r, body, timestamp, nonce))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(protocol.SignatureHeader))) {
+		return config.HMACKey{}, invalidSignature("signature mismatch")
+	}
+
+	// The nonce is recorded only for valid signatures, so that forged
+	// requests cannot fill the cache. It is remembered for as long as the
+	// timestamp would still be accepted.
+	if !verifier.Nonces.Add(key.Id+":"+nonce, signedAt.Add(verifier.MaxSkew), now) {
+		return config.HMACKey{}, invalidSignature("replayed nonce")
+	}
+	return key, nil
+}
+
+func invalidSignature(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSignature, reason)
+}
+
+// NonceCache remembers nonces until they expire. Expired nonces are pruned
+// as new ones are added, at most once a second.
+type NonceCache struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	pruned time.Time
+}
+
+func NewNonceCache() *NonceCache {
+	return &NonceCache{
+		nonces: map[string]time.Time{},
+	}
+}
+
+// Add records nonce until expiresAt and reports whether it was not already
+// recorded.
+func (cache *NonceCache) Add(nonce string, expiresAt time.Time, now time.Time) bool {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if now.Sub(cache.pruned) >= time.Second {
+		for n, expiry := range cache.nonces {
+			if !now.Before(expiry) {
+				delete(cache.nonces, n)
+			}
+		}
+		cache.pruned = now
+	}
+	if _, seen := cache.nonces[nonce]; seen {
+		return false
+	}
+	cache.nonces[nonce] = expiresAt
+	return true
+}
+
+func (cache *NonceCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return len(cache.nonces)
+}
@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/config"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims holds the registered claims of a verified token and the scopes
+// granted by its "scope" (space-separated) or "scp" (array) claim. Raw keeps
+// every claim, including the registered ones.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt *time.Time
+	NotBefore *time.Time
+	IssuedAt  *time.Time
+	Scopes    []string
+	Raw       map[string]interface{}
+}
+
+type verificationKey struct {
+	id  string
+	alg string
+	key interface{}
+}
+
+// JWTVerifier validates HS256, RS256 and ES256 tokens. The algorithm named
+// in a token must match the type of the key that verifies it, so a public
+// RSA key can never be used as an HMAC secret.
+type JWTVerifier struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration
+
+	keys []verificationKey
+}
+
+func NewJWTVerifier(config config.JWTConfig) (*JWTVerifier, error) {
+	verifier := &JWTVerifier{
+		Issuer:   config.Issuer,
+		Audience: config.Audience,
+		Leeway:   time.Duration(config.Leeway),
+	}
+	if config.Secret != "" {
+		verifier.keys = append(verifier.keys, verificationKey{alg: "HS256", key: []byte(config.Secret)})
+	}
+	if config.JWKSFile != "" {
+		keys, err := loadJWKS(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.keys = append(verifier.keys, keys...)
+	}
+	if len(verifier.keys) == 0 {
+		return nil, errors.New("jwt: no secret or jwks file configured")
+	}
+	return verifier, nil
+}
+
+// Verify checks the signature of token and its exp, nbf, iss and aud claims
+// at now.
+func (verifier *JWTVerifier) Verify(token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, invalidToken("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return Claims{}, invalidToken("malformed header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, invalidToken("malformed signature")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range verifier.keys {
+		if key.alg != header.Alg || (header.Kid != "" && key.id != "" && key.id != header.Kid) {
+			continue
+		}
+		if verifySignature(key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return Claims{}, invalidToken("signature not verified")
+	}
+
+	var raw map[string]interface{}
+	err = decodeSegment(parts[1], &raw)
+	if err != nil {
+		return Claims{}, invalidToken("malformed claims")
+	}
+	claims, err := parseClaims(raw)
+	if err != nil {
+		return Claims{}, err
+	}
+	return claims, verifier.validate(claims, now)
+}
+
+func (verifier *JWTVerifier) validate(claims Claims, now time.Time) error {
+	if claims.ExpiresAt != nil && !now.Before(claims.ExpiresAt.Add(verifier.Leeway)) {
+		return invalidToken("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(verifier.Leeway).Before(*claims.NotBefore) {
+		return invalidToken("token not valid yet")
+	}
+	if verifier.Issuer != "" && claims.Issuer != verifier.Issuer {
+		return invalidToken("unexpected issuer")
+	}
+	if verifier.Audience != "" {
+		for _, audience := range claims.Audience {
+			if audience == verifier.Audience {
+				return nil
+			}
+		}
+		return invalidToken("unexpected audience")
+	}
+	return nil
+}
+
+func verifySignature(key verificationKey, signed []byte, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch k := key.key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	default:
+		return false
+	}
+}
+
+func parseClaims(raw map[string]interface{}) (Claims, error) {
+	claims := Claims{Raw: raw}
+	var ok bool
+
+	if value, present := raw["iss"]; present {
+		if claims.Issuer, ok = value.(string); !ok {
+			return claims, invalidToken("iss must be a string")
+		}
+	}
+	if value, present := raw["sub"]; present {
+		if claims.Subject, ok = value.(string); !ok {
+			return claims, invalidToken("sub must be a string")
+		}
+	}
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		claims.Audience = []string{aud}
+	case []interface{}:
+		for _, value := range aud {
+			audience, ok := value.(string)
+			if !ok {
+				return claims, invalidToken("aud must hold strings")
+			}
+			claims.Audience = append(claims.Audience, audience)
+		}
+	default:
+		return claims, invalidToken("aud must be a string or an array")
+	}
+
+	if value, present := raw["scope"]; present {
+		scope, ok := value.(string)
+		if !ok {
+			return claims, invalidToken("scope must be a string")
+		}
+		claims.Scopes = ParseScopes(scope)
+	}
+	switch scp := raw["scp"].(type) {
+	case nil:
+	case string:
+		claims.Scopes = append(claims.Scopes, ParseScopes(scp)...)
+	case []interface{}:
+		for _, value := range scp {
+			scope, ok := value.(string)
+			if !ok {
+				return claims, invalidToken("scp must hold strings")
+			}
+			claims.Scopes = append(claims.Scopes, scope)
+		}
+	default:
+		return claims, invalidToken("scp must be a string or an array")
+	}
+
+	for name, target := range map[string]**time.Time{"exp": &claims.ExpiresAt, "nbf": &claims.NotBefore, "iat": &claims.IssuedAt} {
+		value, present := raw[name]
+		if !present {
+			continue
+		}
+		seconds, ok := value.(float64)
+		if !ok {
+			return claims, invalidToken(name + " must be a number")
+		}
+		if math.IsNaN(seconds) || math.IsInf(seconds, 0) || seconds > maxTimestamp || seconds < -maxTimestamp {
+			return claims, invalidToken(name + " out of range")
+		}
+		sec, frac := math.Modf(seconds)
+		t := time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		*target = &t
+	}
+	return claims, nil
+}
+
+// maxTimestamp bounds exp, nbf and iat, in seconds, so that they convert to
+// times whose distance from now fits in a time.Duration instead of wrapping
+// around.
+const maxTimestamp = float64(1<<62) / 1e9
+
+func decodeSegment(segment string, v interface{}) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidToken, reason)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// loadJWKS reads RSA, P-256 EC and symmetric (oct) keys from a JSON Web Key
+// Set. Keys meant for encryption are skipped.
+func loadJWKS(path string) ([]verificationKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = json.Unmarshal(content, &set)
+	if err != nil {
+		return nil, fmt.Errorf("jwks file %s: %w", path, err)
+	}
+
+	var keys []verificationKey
+	for i, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		key, err := k.verificationKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks file %s: key %d: %w", path, i, err)
+		}
+		if k.Alg != "" && k.Alg != key.alg {
+			return nil, fmt.Errorf("jwks file %s: key %d: unsupported alg %q", path, i, k.Alg)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (k jwk) verificationKey() (verificationKey, error) {
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		return verificationKey{id: k.Kid, alg: "HS256", key: secret}, nil
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		return verificationKey{id: k.Kid, alg: "RS256", key: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return verificationKey{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return verificationKey{}, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return verificationKey{}, errors.New("point is not on P-256")
+		}
+		return verificationKey{id: k.Kid, alg: "ES256", key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported kty %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	content, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(content), nil
+}
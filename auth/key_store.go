@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/model/domain"
+)
+
+var (
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrKeyExpired  = errors.New("api key expired")
+	ErrKeyRevoked  = errors.New("api key revoked")
+)
+
+// LastUsedResolution limits how often a key's last_used_at is written, so
+// that busy clients do not cause a write on every request.
+const LastUsedResolution = time.Minute
+
+type KeyStore interface {
+	Save(ctx context.Context, key domain.APIKey) (domain.APIKey, error)
+	FindByHash(ctx context.Context, hash string) (domain.APIKey, error)
+	FindAll(ctx context.Context) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, keyId int64, at time.Time) error
+	Touch(ctx context.Context, keyId int64, at time.Time) error
+}
+
+// GenerateKey returns a new random key. It is shown to the caller once; only
+// its hash is stored.
+func GenerateKey() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateKey stores a newly generated key and returns it with its plaintext.
+func CreateKey(ctx context.Context, store KeyStore, name string, scopes []string, expiresAt *time.Time) (domain.APIKey, string, error) {
+	plaintext, err := GenerateKey()
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+	key, err := store.Save(ctx, domain.APIKey{
+		Name:      name,
+		Hash:      HashKey(plaintext),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	})
+	return key, plaintext, err
+}
+
+// EnsureKey stores plaintext under name with scopes unless a key with the
+// same hash already exists, whose scopes are then left as they are. It
+// registers the key from auth.api_key at startup.
+func EnsureKey(ctx context.Context, store KeyStore, name string, plaintext string, scopes []string) (domain.APIKey, error) {
+	key, err := store.FindByHash(ctx, HashKey(plaintext))
+	if !errors.Is(err, ErrKeyNotFound) {
+		return key, err
+	}
+	return store.Save(ctx, domain.APIKey{
+		Name:      name,
+		Hash:      HashKey(plaintext),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// Authenticate returns the stored key matching plaintext if it is neither
+// revoked nor expired at now, and records its use.
+func Authenticate(ctx context.Context, store KeyStore, plaintext string, now time.Time) (domain.APIKey, error) {
+	if plaintext == "" {
+		return domain.APIKey{}, ErrKeyNotFound
+	}
+
+	hash := HashKey(plaintext)
+	key, err := store.FindByHash(ctx, hash)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) != 1 {
+		return domain.APIKey{}, ErrKeyNotFound
+	}
+	if key.RevokedAt != nil && !now.Before(*key.RevokedAt) {
+		return key, ErrKeyRevoked
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return key, ErrKeyExpired
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= LastUsedResolution {
+		err = store.Touch(ctx, key.Id, now)
+		if err != nil {
+			return key, err
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/model/domain"
+)
+
+// MemoryKeyStore keeps keys in a map. It is meant for tests and for running
+// with the single key from auth.api_key.
+type MemoryKeyStore struct {
+	mu     sync.RWMutex
+	keys   map[int64]domain.APIKey
+	lastId int64
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys: map[int64]domain.APIKey{},
+	}
+}
+
+func (store *MemoryKeyStore) Save(ctx context.Context, key domain.APIKey) (domain.APIKey, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.lastId++
+	key.Id = store.lastId
+	store.keys[key.Id] = key
+	return key, nil
+}
+
+// FindByHash compares hash with every stored key in constant time, without
+// stopping at the first match.
+func (store *MemoryKeyStore) FindByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var found domain.APIKey
+	ok := false
+	for _, key := range store.keys {
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) == 1 {
+			found, ok = key, true
+		}
+	}
+	if !ok {
+		return domain.APIKey{}, ErrKeyNotFound
+	}
+	return found, nil
+}
+
+func (store *MemoryKeyStore) FindAll(ctx context.Context) ([]domain.APIKey, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	var keys []domain.APIKey
+	for _, key := range store.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Id < keys[j].Id
+	})
+	return keys, nil
+}
+
+func (store *MemoryKeyStore) Revoke(ctx context.Context, keyId int64, at time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key, ok := store.keys[keyId]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	key.RevokedAt = &at
+	store.keys[keyId] = key
+	return nil
+}
+
+func (store *MemoryKeyStore) Touch(ctx context.Context, keyId int64, at time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	key, ok := store.keys[keyId]
+	if !ok {
+		return ErrKeyNotFound
+	}
+	key.LastUsedAt = &at
+	store.keys[keyId] = key
+	return nil
+}
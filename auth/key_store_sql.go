@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/db"
+	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/model/domain"
+)
+
+// SQLKeyStore keeps keys in the api_key table. Lookups go through the unique
+// index on key_hash.
+type SQLKeyStore struct {
+	DB      *sql.DB
+	Dialect db.Dialect
+}
+
+func NewSQLKeyStore(DB *sql.DB, dialect db.Dialect) *SQLKeyStore {
+	return &SQLKeyStore{
+		DB:      DB,
+		Dialect: dialect,
+	}
+}
+
+func (store *SQLKeyStore) Save(ctx context.Context, key domain.APIKey) (_ domain.APIKey, err error) {
+	tx, err := store.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return key, err
+	}
+	defer helper.CommitOrRollback(tx, &err)
+
+	SQL := "INSERT INTO api_key(name, key_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?)"
+	id, err := store.Dialect.InsertReturningId(ctx, tx, SQL, key.Name, key.Hash, FormatScopes(key.Scopes), key.CreatedAt.UTC(), utcOrNil(key.ExpiresAt))
+	if err != nil {
+		return key, err
+	}
+
+	key.Id = id
+	return key, nil
+}
+
+func (store *SQLKeyStore) FindByHash(ctx context.Context, hash string) (domain.APIKey, error) {
+	SQL := "SELECT id, name, key_hash, scopes, created_at, expires_at, revoked_at, last_used_at FROM api_key WHERE key_hash = ?"
+	keys, err := store.queryKeys(ctx, SQL, hash)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	if len(keys) == 0 {
+		return domain.APIKey{}, ErrKeyNotFound
+	}
+	return keys[0], nil
+}
+
+func (store *SQLKeyStore) FindAll(ctx context.Context) ([]domain.APIKey, error) {
+	SQL := "SELECT id, name, key_hash, scopes, created_at, expires_at, revoked_at, last_used_at FROM api_key ORDER BY id"
+	return store.queryKeys(ctx, SQL)
+}
+
+func (store *SQLKeyStore) Revoke(ctx context.Context, keyId int64, at time.Time) error {
+	SQL := "UPDATE api_key SET revoked_at = ? WHERE id = ?"
+	return store.exec(ctx, SQL, at.UTC(), keyId)
+}
+
+func (store *SQLKeyStore) Touch(ctx context.Context, keyId int64, at time.Time) error {
+	SQL := "UPDATE api_key SET last_used_at = ? WHERE id = ?"
+	return store.exec(ctx, SQL, at.UTC(), keyId)
+}
+
+func (store *SQLKeyStore) exec(ctx context.Context, SQL string, args ...interface{}) error {
+	result, err := store.DB.ExecContext(ctx, store.Dialect.Rebind(SQL), args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+func (store *SQLKeyStore) queryKeys(ctx context.Context, SQL string, args ...interface{}) ([]domain.APIKey, error) {
+	resRows, err := store.DB.QueryContext(ctx, store.Dialect.Rebind(SQL), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer resRows.Close()
+
+	var keys []domain.APIKey
+	for resRows.Next() {
+		key := domain.APIKey{}
+		var scopes string
+		var expiresAt, revokedAt, lastUsedAt sql.NullTime
+		err = resRows.Scan(&key.Id, &key.Name, &key.Hash, &scopes, &key.CreatedAt, &expiresAt, &revokedAt, &lastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		key.Scopes = ParseScopes(scopes)
+		key.ExpiresAt = timeOrNil(expiresAt)
+		key.RevokedAt = timeOrNil(revokedAt)
+		key.LastUsedAt = timeOrNil(lastUsedAt)
+		keys = append(keys, key)
+	}
+	return keys, resRows.Err()
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func timeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
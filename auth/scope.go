@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	ScopeCategoriesRead   = "categories:read"
+	ScopeCategoriesWrite  = "categories:write"
+	ScopeCategoriesDelete = "categories:delete"
+)
+
+// ScopesFromContext returns the scopes granted to the API key, bearer token
+// or signing key the request was authenticated with.
+func ScopesFromContext(ctx context.Context) []string {
+	if key, ok := KeyFromContext(ctx); ok {
+		return key.Scopes
+	}
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.Scopes
+	}
+	if signer, ok := SignerFromContext(ctx); ok {
+		return signer.Scopes
+	}
+	return nil
+}
+
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScopes splits a space-separated scope list, the format of the OAuth
+// "scope" claim and of the api_key.scopes column.
+func ParseScopes(value string) []string {
+	return strings.Fields(value)
+}
+
+func FormatScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
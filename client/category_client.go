@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+
+	webrequest "github.com/rtanx/golang-restful-api/model/web/request"
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+)
+
+// CategoryClient has the methods of service.CategoryService, so it can stand
+// in for the service of another process. FindAll and FindChildren set Meta
+// to a webresponse.CursorMeta when the request has a cursor and to a
+// webresponse.PageMeta otherwise. Update, Move and Delete send IfMatch as the
+// If-Match header, so that they fail with PreconditionFailedError when the
+// category has another version.
+type CategoryClient interface {
+	Create(ctx context.Context, request webrequest.CategoryCreateRequest) (webresponse.CategoryResponse, error)
+	Update(ctx context.Context, request webrequest.CategoryUpdateRequest) (webresponse.CategoryResponse, error)
+	Delete(ctx context.Context, request webrequest.CategoryDeleteRequest) error
+	FindById(ctx context.Context, categoryId int64) (webresponse.CategoryResponse, error)
+	FindAll(ctx context.Context, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error)
+	Move(ctx context.Context, request webrequest.CategoryMoveRequest) (webresponse.CategoryResponse, error)
+	FindChildren(ctx context.Context, categoryId int64, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error)
+	FindAncestors(ctx context.Context, categoryId int64) ([]webresponse.CategoryResponse, error)
+	FindSubtree(ctx context.Context, categoryId int64) (webresponse.CategoryTreeResponse, error)
+	// ForEach calls fn with every category FindAll lists for request,
+	// following next_cursor from page to page, until fn returns an error.
+	ForEach(ctx context.Context, request webrequest.CategoryListRequest, fn func(webresponse.CategoryResponse) error) error
+}
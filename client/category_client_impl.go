@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	webrequest "github.com/rtanx/golang-restful-api/model/web/request"
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/protocol"
+)
+
+type CategoryClientImpl struct {
+	Client *Client
+}
+
+func NewCategoryClient(client *Client) CategoryClient {
+	return &CategoryClientImpl{Client: client}
+}
+
+func (c *CategoryClientImpl) Create(ctx context.Context, request webrequest.CategoryCreateRequest) (webresponse.CategoryResponse, error) {
+	var category webresponse.CategoryResponse
+	err := c.Client.Do(ctx, http.MethodPost, "/api/categories", nil, nil, request, &category, nil)
+	return category, err
+}
+
+func (c *CategoryClientImpl) Update(ctx context.Context, request webrequest.CategoryUpdateRequest) (webresponse.CategoryResponse, error) {
+	var category webresponse.CategoryResponse
+	err := c.Client.Do(ctx, http.MethodPut, categoryPath(request.Id), nil, ifMatch(request.IfMatch), request, &category, nil)
+	return category, err
+}
+
+func (c *CategoryClientImpl) Delete(ctx context.Context, request webrequest.CategoryDeleteRequest) error {
+	return c.Client.Do(ctx, http.MethodDelete, categoryPath(request.Id), nil, ifMatch(request.IfMatch), nil, nil, nil)
+}
+
+func (c *CategoryClientImpl) FindById(ctx context.Context, categoryId int64) (webresponse.CategoryResponse, error) {
+	var category webresponse.CategoryResponse
+	err := c.Client.Do(ctx, http.MethodGet, categoryPath(categoryId), nil, nil, nil, &category, nil)
+	return category, err
+}
+
+func (c *CategoryClientImpl) FindAll(ctx context.Context, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error) {
+	return c.list(ctx, "/api/categories", request)
+}
+
+func (c *CategoryClientImpl) Move(ctx context.Context, request webrequest.CategoryMoveRequest) (webresponse.CategoryResponse, error) {
+	var category webresponse.CategoryResponse
+	err := c.Client.Do(ctx, http.MethodPost, categoryPath(request.Id)+"/move", nil, ifMatch(request.IfMatch), request, &category, nil)
+	return category, err
+}
+
+func (c *CategoryClientImpl) FindChildren(ctx context.Context, categoryId int64, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error) {
+	return c.list(ctx, categoryPath(categoryId)+"/children", request)
+}
+
+func (c *CategoryClientImpl) FindAncestors(ctx context.Context, categoryId int64) ([]webresponse.CategoryResponse, error) {
+	var categories []webresponse.CategoryResponse
+	err := c.Client.Do(ctx, http.MethodGet, categoryPath(categoryId)+"/ancestors", nil, nil, nil, &categories, nil)
+	return categories, err
+}
+
+func (c *CategoryClientImpl) FindSubtree(ctx context.Context, categoryId int64) (webresponse.CategoryTreeResponse, error) {
+	var tree webresponse.CategoryTreeResponse
+	err := c.Client.Do(ctx, http.MethodGet, categoryPath(categoryId)+"/subtree", nil, nil, nil, &tree, nil)
+	return tree, err
+}
+
+func (c *CategoryClientImpl) ForEach(ctx context.Context, request webrequest.CategoryListRequest, fn func(webresponse.CategoryResponse) error) error {
+	for {
+		response, err := c.FindAll(ctx, request)
+		if err != nil {
+			return err
+		}
+		for _, category := range response.Categories {
+			err = fn(category)
+			if err != nil {
+				return err
+			}
+		}
+
+		var next string
+		switch meta := response.Meta.(type) {
+		case webresponse.CursorMeta:
+			next = meta.NextCursor
+		case webresponse.PageMeta:
+			next = meta.NextCursor
+		}
+		if next == "" {
+			return nil
+		}
+		// The cursor replaces the offset the first page may have had.
+		request.Cursor = next
+		request.Page = 0
+		request.Offset = 0
+	}
+}
+
+func (c *CategoryClientImpl) list(ctx context.Context, path string, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error) {
+	var response webresponse.CategoryListResponse
+	var err error
+	if request.Cursor != "" {
+		var meta webresponse.CursorMeta
+		err = c.Client.Do(ctx, http.MethodGet, path, listQuery(request), nil, nil, &response.Categories, &meta)
+		response.Meta = meta
+	} else {
+		var meta webresponse.PageMeta
+		err = c.Client.Do(ctx, http.MethodGet, path, listQuery(request), nil, nil, &response.Categories, &meta)
+		response.Meta = meta
+	}
+	return response, err
+}
+
+// listQuery is the inverse of the controller's readCategoryListRequest.
+func listQuery(request webrequest.CategoryListRequest) url.Values {
+	query := url.Values{}
+	params := map[string]string{
+		"sort":       request.Sort,
+		"name":       request.Name,
+		"name_match": request.NameMatch,
+		"cursor":     request.Cursor,
+	}
+	for name, value := range params {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	intParams := map[string]int{
+		"page":   request.Page,
+		"size":   request.Size,
+		"limit":  request.Limit,
+		"offset": request.Offset,
+	}
+	for name, value := range intParams {
+		if value != 0 {
+			query.Set(name, strconv.Itoa(value))
+		}
+	}
+	return query
+}
+
+func ifMatch(versions []int64) http.Header {
+	if len(versions) == 0 {
+		return nil
+	}
+	var tags []string
+	for _, version := range versions {
+		tags = append(tags, protocol.ETag(version))
+	}
+	return http.Header{"If-Match": {strings.Join(tags, ", ")}}
+}
+
+func categoryPath(categoryId int64) string {
+	return "/api/categories/" + strconv.FormatInt(categoryId, 10)
+}
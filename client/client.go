@@ -0,0 +1,275 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/protocol"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Credentials authenticate a request before it is sent. body is the exact
+// request body, which signatures cover.
+type Credentials interface {
+	Apply(request *http.Request, body []byte) error
+}
+
+// APIKey is sent in the X-API-KEY header.
+type APIKey string
+
+func (key APIKey) Apply(request *http.Request, body []byte) error {
+	request.Header.Set("X-API-KEY", string(key))
+	return nil
+}
+
+// BearerToken is sent in the Authorization header, usually a JWT.
+type BearerToken string
+
+func (token BearerToken) Apply(request *http.Request, body []byte) error {
+	request.Header.Set("Authorization", "Bearer "+string(token))
+	return nil
+}
+
+// HMACKey signs each request with protocol.SignRequest.
+type HMACKey struct {
+	Id     string
+	Secret string
+}
+
+func (key HMACKey) Apply(request *http.Request, body []byte) error {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	protocol.SignRequest(request, body, key.Id, key.Secret, time.Now(), hex.EncodeToString(nonce))
+	return nil
+}
+
+// Client sends requests to the API at BaseURL, e.g. http://localhost:8080.
+// Idempotent requests that fail with a network error, 429, 502, 503 or 504
+// are retried up to MaxRetries times, waiting for Retry-After if the server
+// sent one and an exponentially growing, jittered backoff otherwise.
+type Client struct {
+	BaseURL     string
+	HTTPClient  *http.Client
+	Credentials Credentials
+	MaxRetries  int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+func NewClient(baseURL string, credentials Credentials) *Client {
+	return &Client{
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+		Credentials: credentials,
+		MaxRetries:  3,
+		MinBackoff:  100 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+	}
+}
+
+// Do sends a request with header and with in marshalled as its JSON body, if
+// not nil, and decodes the WebResponse it gets back. Its data and meta are
+// decoded into data and meta, if not nil.
+func (client *Client) Do(ctx context.Context, method string, path string, query url.Values, header http.Header, in interface{}, data interface{}, meta interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+	target := client.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	idempotent := method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+	for attempt := 0; ; attempt++ {
+		response, err := client.send(ctx, method, target, header, body)
+		if err == nil {
+			err = decodeResponse(response, data, meta)
+		}
+		if err == nil || !idempotent || attempt >= client.MaxRetries || !retryable(ctx, err) {
+			return err
+		}
+
+		delay := client.backoff(attempt)
+		var tooManyRequests TooManyRequestsError
+		if errors.As(err, &tooManyRequests) && tooManyRequests.RetryAfter > 0 {
+			delay = tooManyRequests.RetryAfter
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (client *Client) send(ctx context.Context, method string, target string, header http.Header, body []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("Accept", protocol.ProblemContentType+", application/json;q=0.9")
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(request.Header))
+	if client.Credentials != nil {
+		err = client.Credentials.Apply(request, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return client.HTTPClient.Do(request)
+}
+
+func decodeResponse(response *http.Response, data interface{}, meta interface{}) error {
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode/100 != 2 {
+		return newError(response, body)
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+		Meta json.RawMessage `json:"meta"`
+	}
+	err = json.Unmarshal(body, &envelope)
+	if err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	if data != nil && len(envelope.Data) > 0 {
+		err = json.Unmarshal(envelope.Data, data)
+		if err != nil {
+			return fmt.Errorf("decoding response data: %w", err)
+		}
+	}
+	if meta != nil && len(envelope.Meta) > 0 {
+		err = json.Unmarshal(envelope.Meta, meta)
+		if err != nil {
+			return fmt.Errorf("decoding response meta: %w", err)
+		}
+	}
+	return nil
+}
+
+// newError decodes a problem or, from middleware that does not write
+// problems, a WebResponse.
+func newError(response *http.Response, body []byte) error {
+	apiErr := &APIError{
+		StatusCode: response.StatusCode,
+		Message:    http.StatusText(response.StatusCode),
+		RequestID:  response.Header.Get("X-Request-ID"),
+	}
+
+	var problem webresponse.ProblemResponse
+	var envelope webresponse.WebResponse
+	switch {
+	case strings.HasPrefix(response.Header.Get("Content-Type"), protocol.ProblemContentType) && json.Unmarshal(body, &problem) == nil:
+		if problem.Detail != "" {
+			apiErr.Message = problem.Detail
+		}
+		for _, field := range problem.Errors {
+			if apiErr.Fields == nil {
+				apiErr.Fields = map[string]string{}
+			}
+			apiErr.Fields[field.Field] = field.Message
+			if field.Message == "" {
+				apiErr.Fields[field.Field] = field.Rule
+			}
+		}
+	case json.Unmarshal(body, &envelope) == nil:
+		switch data := envelope.Data.(type) {
+		case string:
+			if data != "" {
+				apiErr.Message = data
+			}
+		case map[string]interface{}:
+			apiErr.Fields = map[string]string{}
+			for field, message := range data {
+				apiErr.Fields[field] = fmt.Sprint(message)
+			}
+		}
+	}
+
+	switch response.StatusCode {
+	case http.StatusBadRequest:
+		return BadRequestError{apiErr}
+	case http.StatusUnauthorized:
+		return UnauthorizedError{apiErr}
+	case http.StatusForbidden:
+		return ForbiddenError{apiErr}
+	case http.StatusNotFound:
+		return NotFoundError{apiErr}
+	case http.StatusConflict:
+		return ConflictError{apiErr}
+	case http.StatusPreconditionFailed:
+		return PreconditionFailedError{apiErr}
+	case http.StatusPreconditionRequired:
+		return PreconditionRequiredError{apiErr}
+	case http.StatusTooManyRequests:
+		seconds, _ := strconv.Atoi(response.Header.Get("Retry-After"))
+		return TooManyRequestsError{APIError: apiErr, RetryAfter: time.Duration(seconds) * time.Second}
+	case http.StatusInternalServerError:
+		return InternalServerError{apiErr}
+	default:
+		return apiErr
+	}
+}
+
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// A network error; the request may not have reached the server.
+		return true
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns a random delay up to MinBackoff doubled attempt times,
+// capped at MaxBackoff.
+func (client *Client) backoff(attempt int) time.Duration {
+	ceiling := client.MinBackoff << uint(attempt)
+	if ceiling > client.MaxBackoff || ceiling <= 0 {
+		ceiling = client.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(mathrand.Int63n(int64(ceiling)) + 1)
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIError is an error response of the API. The typed errors below embed it
+// and mirror the exception package, so callers can match a status with
+// errors.As.
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Fields maps the invalid fields of a 400 to their messages, or to the
+	// failed validation rule when the server did not translate them.
+	Fields    map[string]string
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+}
+
+type BadRequestError struct {
+	*APIError
+}
+
+func (e BadRequestError) Unwrap() error {
+	return e.APIError
+}
+
+type UnauthorizedError struct {
+	*APIError
+}
+
+func (e UnauthorizedError) Unwrap() error {
+	return e.APIError
+}
+
+type ForbiddenError struct {
+	*APIError
+}
+
+func (e ForbiddenError) Unwrap() error {
+	return e.APIError
+}
+
+type NotFoundError struct {
+	*APIError
+}
+
+func (e NotFoundError) Unwrap() error {
+	return e.APIError
+}
+
+type ConflictError struct {
+	*APIError
+}
+
+func (e ConflictError) Unwrap() error {
+	return e.APIError
+}
+
+// PreconditionFailedError means that the category changed since the version
+// sent in IfMatch was read.
+type PreconditionFailedError struct {
+	*APIError
+}
+
+func (e PreconditionFailedError) Unwrap() error {
+	return e.APIError
+}
+
+type PreconditionRequiredError struct {
+	*APIError
+}
+
+func (e PreconditionRequiredError) Unwrap() error {
+	return e.APIError
+}
+
+// TooManyRequestsError carries the Retry-After the rate limiter sent, zero
+// if it sent none.
+type TooManyRequestsError struct {
+	*APIError
+	RetryAfter time.Duration
+}
+
+func (e TooManyRequestsError) Unwrap() error {
+	return e.APIError
+}
+
+type InternalServerError struct {
+	*APIError
+}
+
+func (e InternalServerError) Unwrap() error {
+	return e.APIError
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/openapi"
+)
+
+// openapi-gen writes the OpenAPI document generated from app.Routes to -o,
+// or with -check exits with an error if the file there differs from it.
+func main() {
+	output := flag.String("o", "apispec.json", "file to write the document to")
+	check := flag.Bool("check", false, "fail if the file is not up to date instead of writing it")
+	flag.Parse()
+
+	spec, err := openapi.Marshal(app.NewOpenAPI())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *check {
+		committed, err := os.ReadFile(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !bytes.Equal(committed, spec) {
+			log.Fatalf("%s is out of date, run go generate ./openapi", *output)
+		}
+		return
+	}
+
+	err = os.WriteFile(*output, spec, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,541 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server      ServerConfig      `yaml:"server" json:"server"`
+	Database    DatabaseConfig    `yaml:"database" json:"database"`
+	Auth        AuthConfig        `yaml:"auth" json:"auth"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" json:"rate_limit"`
+	Metrics     MetricsConfig     `yaml:"metrics" json:"metrics"`
+	Tracing     TracingConfig     `yaml:"tracing" json:"tracing"`
+	OpenAPI     OpenAPIConfig     `yaml:"openapi" json:"openapi"`
+	Concurrency ConcurrencyConfig `yaml:"concurrency" json:"concurrency"`
+}
+
+type ServerConfig struct {
+	Host              string   `yaml:"host" json:"host"`
+	Port              int      `yaml:"port" json:"port"`
+	ReadTimeout       Duration `yaml:"read_timeout" json:"read_timeout"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" json:"read_header_timeout"`
+	WriteTimeout      Duration `yaml:"write_timeout" json:"write_timeout"`
+	IdleTimeout       Duration `yaml:"idle_timeout" json:"idle_timeout"`
+	ShutdownTimeout   Duration `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+	ShutdownDelay     Duration `yaml:"shutdown_delay" json:"shutdown_delay"`
+	ReadinessTimeout  Duration `yaml:"readiness_timeout" json:"readiness_timeout"`
+}
+
+type DatabaseConfig struct {
+	Dialect         string   `yaml:"dialect" json:"dialect"`
+	DSN             string   `yaml:"dsn" json:"dsn"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" json:"max_idle_conns"`
+	MaxOpenConns    int      `yaml:"max_open_conns" json:"max_open_conns"`
+	ConnMaxIdleTime Duration `yaml:"conn_max_idle_time" json:"conn_max_idle_time"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" json:"conn_max_lifetime"`
+	AutoMigrate     bool     `yaml:"auto_migrate" json:"auto_migrate"`
+}
+
+type AuthConfig struct {
+	Methods      []string   `yaml:"methods" json:"methods"`
+	APIKey       string     `yaml:"api_key" json:"api_key"`
+	APIKeyScopes []string   `yaml:"api_key_scopes" json:"api_key_scopes"`
+	KeyStore     string     `yaml:"key_store" json:"key_store"`
+	JWT          JWTConfig  `yaml:"jwt" json:"jwt"`
+	HMAC         HMACConfig `yaml:"hmac" json:"hmac"`
+}
+
+// HasMethod reports whether method, "api_key", "jwt" or "hmac", is enabled.
+func (config AuthConfig) HasMethod(method string) bool {
+	for _, m := range config.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTConfig configures bearer tokens. Secret verifies HS256 tokens;
+// JWKSFile names a JSON Web Key Set holding RS256, ES256 or HS256 keys.
+// Issuer and Audience, when set, must match the iss and aud claims.
+type JWTConfig struct {
+	Secret   string   `yaml:"secret" json:"secret"`
+	JWKSFile string   `yaml:"jwks_file" json:"jwks_file"`
+	Issuer   string   `yaml:"issuer" json:"issuer"`
+	Audience string   `yaml:"audience" json:"audience"`
+	Leeway   Duration `yaml:"leeway" json:"leeway"`
+}
+
+// HMACConfig configures signed requests. Each key's secret is shared with
+// one caller; MaxSkew bounds how far a signature's timestamp may be from the
+// server clock.
+type HMACConfig struct {
+	Keys    []HMACKey `yaml:"keys" json:"keys"`
+	MaxSkew Duration  `yaml:"max_skew" json:"max_skew"`
+}
+
+type HMACKey struct {
+	Id     string   `yaml:"id" json:"id"`
+	Secret string   `yaml:"secret" json:"secret"`
+	Scopes []string `yaml:"scopes" json:"scopes"`
+}
+
+// MetricsConfig controls the Prometheus endpoint at /metrics, which like the
+// health probes is served without authentication.
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+}
+
+// OpenAPIConfig controls the bundled OpenAPI document: Docs serves it at
+// /openapi.json with a Swagger UI page at /docs, loading the UI from
+// DocsAssetsURL, ValidateRequests rejects requests that do not match it and
+// ValidateResponses logs responses that do not. Validated request bodies are
+// read into memory, up to MaxBodyBytes.
+type OpenAPIConfig struct {
+	Docs              bool   `yaml:"docs" json:"docs"`
+	DocsAssetsURL     string `yaml:"docs_assets_url" json:"docs_assets_url"`
+	ValidateRequests  bool   `yaml:"validate_requests" json:"validate_requests"`
+	ValidateResponses bool   `yaml:"validate_responses" json:"validate_responses"`
+	MaxBodyBytes      int    `yaml:"max_body_bytes" json:"max_body_bytes"`
+}
+
+// ConcurrencyConfig controls optimistic concurrency on categories. With
+// RequireIfMatch, the default, updates, moves and deletes without an
+// If-Match header are rejected with 428 Precondition Required; without it
+// such requests overwrite whatever version is current.
+type ConcurrencyConfig struct {
+	RequireIfMatch bool `yaml:"require_if_match" json:"require_if_match"`
+}
+
+// TracingConfig selects where spans are exported: "none", "stdout" or
+// "file" (JSON lines) or "otlp" (OTLP/HTTP to OTLPEndpoint). SampleRatio of
+// the traces started here are kept; continued traces follow the caller.
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" json:"exporter"`
+	File         string  `yaml:"file" json:"file"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" json:"otlp_endpoint"`
+	ServiceName  string  `yaml:"service_name" json:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio" json:"sample_ratio"`
+}
+
+// RateLimitConfig holds a token bucket per route group; app.NewRouter puts
+// reads in "read" and writes and deletes in "write". Each client has its own
+// bucket in every group. The "ip" group limits every request by client IP
+// address before authentication, which bounds credential guessing.
+type RateLimitConfig struct {
+	Enabled bool                      `yaml:"enabled" json:"enabled"`
+	Groups  map[string]RateLimitGroup `yaml:"groups" json:"groups"`
+}
+
+// RateLimitGroup allows Requests per Per, in bursts of up to Burst requests.
+// A zero Burst means Requests.
+type RateLimitGroup struct {
+	Requests int      `yaml:"requests" json:"requests"`
+	Per      Duration `yaml:"per" json:"per"`
+	Burst    int      `yaml:"burst" json:"burst"`
+}
+
+// Duration accepts time.ParseDuration strings such as "10m" in config files.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+var dialects = []string{"mysql", "postgres", "postgresql", "sqlite", "sqlite3"}
+
+var keyStores = []string{"sql", "memory"}
+
+var authMethods = []string{"api_key", "jwt", "hmac"}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Host:              "localhost",
+			Port:              8080,
+			ReadTimeout:       Duration(15 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(30 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			ShutdownTimeout:   Duration(30 * time.Second),
+			ShutdownDelay:     Duration(5 * time.Second),
+			ReadinessTimeout:  Duration(2 * time.Second),
+		},
+		Database: DatabaseConfig{
+			Dialect:         "mysql",
+			DSN:             "root:root@tcp(localhost:3306)/learn_golang_restful_api?parseTime=true",
+			MaxIdleConns:    5,
+			MaxOpenConns:    20,
+			ConnMaxIdleTime: Duration(60 * time.Minute),
+			ConnMaxLifetime: Duration(10 * time.Minute),
+		},
+		Auth: AuthConfig{
+			Methods:      []string{"api_key"},
+			APIKeyScopes: []string{"categories:read", "categories:write", "categories:delete"},
+			KeyStore:     "sql",
+			JWT: JWTConfig{
+				Leeway: Duration(30 * time.Second),
+			},
+			HMAC: HMACConfig{
+				MaxSkew: Duration(5 * time.Minute),
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Groups: map[string]RateLimitGroup{
+				"read":  {Requests: 300, Per: Duration(time.Minute), Burst: 60},
+				"write": {Requests: 60, Per: Duration(time.Minute), Burst: 20},
+				"ip":    {Requests: 600, Per: Duration(time.Minute), Burst: 120},
+			},
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "golang-restful-api",
+			SampleRatio: 1,
+		},
+		OpenAPI: OpenAPIConfig{
+			Docs:          true,
+			DocsAssetsURL: "https://unpkg.com/swagger-ui-dist@5",
+			MaxBodyBytes:  1 << 20,
+		},
+		Concurrency: ConcurrencyConfig{
+			RequireIfMatch: true,
+		},
+	}
+}
+
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(value string) error
+}
+
+func (config *Config) settings() []setting {
+	return []setting{
+		{"SERVER_HOST", "host", "address the HTTP server listens on", stringSetter(&config.Server.Host)},
+		{"SERVER_PORT", "port", "port the HTTP server listens on", intSetter(&config.Server.Port)},
+		{"SERVER_READ_TIMEOUT", "read-timeout", "maximum time to read a request, body included", durationSetter(&config.Server.ReadTimeout)},
+		{"SERVER_READ_HEADER_TIMEOUT", "read-header-timeout", "maximum time to read request headers", durationSetter(&config.Server.ReadHeaderTimeout)},
+		{"SERVER_WRITE_TIMEOUT", "write-timeout", "maximum time to write a response", durationSetter(&config.Server.WriteTimeout)},
+		{"SERVER_IDLE_TIMEOUT", "idle-timeout", "how long a keep-alive connection may stay idle", durationSetter(&config.Server.IdleTimeout)},
+		{"SERVER_SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long shutdown waits for in-flight requests", durationSetter(&config.Server.ShutdownTimeout)},
+		{"SERVER_SHUTDOWN_DELAY", "shutdown-delay", "how long /readyz reports shutdown before the server stops accepting connections", durationSetter(&config.Server.ShutdownDelay)},
+		{"SERVER_READINESS_TIMEOUT", "readiness-timeout", "how long each /readyz check may take", durationSetter(&config.Server.ReadinessTimeout)},
+		{"DB_DIALECT", "db-dialect", "SQL dialect: mysql, postgres or sqlite", stringSetter(&config.Database.Dialect)},
+		{"DB_DSN", "db-dsn", "data source name passed to the SQL driver", stringSetter(&config.Database.DSN)},
+		{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum idle connections in the pool", intSetter(&config.Database.MaxIdleConns)},
+		{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum open connections in the pool", intSetter(&config.Database.MaxOpenConns)},
+		{"DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "how long a connection may stay idle", durationSetter(&config.Database.ConnMaxIdleTime)},
+		{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "how long a connection may be reused", durationSetter(&config.Database.ConnMaxLifetime)},
+		{"DB_AUTO_MIGRATE", "db-auto-migrate", "apply pending migrations at startup", boolSetter(&config.Database.AutoMigrate)},
+		{"AUTH_METHODS", "auth-methods", "comma-separated authentication methods: api_key, jwt, hmac", listSetter(&config.Auth.Methods)},
+		{"AUTH_API_KEY", "api-key", "key registered in the key store at startup", stringSetter(&config.Auth.APIKey)},
+		{"AUTH_API_KEY_SCOPES", "api-key-scopes", "comma-separated scopes granted to the startup key", listSetter(&config.Auth.APIKeyScopes)},
+		{"AUTH_KEY_STORE", "auth-key-store", "where API keys are kept: sql or memory", stringSetter(&config.Auth.KeyStore)},
+		{"AUTH_JWT_SECRET", "jwt-secret", "secret that verifies HS256 bearer tokens", stringSetter(&config.Auth.JWT.Secret)},
+		{"AUTH_JWT_JWKS_FILE", "jwt-jwks-file", "JWKS file with keys that verify bearer tokens", stringSetter(&config.Auth.JWT.JWKSFile)},
+		{"AUTH_JWT_ISSUER", "jwt-issuer", "required iss claim of bearer tokens", stringSetter(&config.Auth.JWT.Issuer)},
+		{"AUTH_JWT_AUDIENCE", "jwt-audience", "required aud claim of bearer tokens", stringSetter(&config.Auth.JWT.Audience)},
+		{"AUTH_JWT_LEEWAY", "jwt-leeway", "clock skew allowed when checking exp and nbf", durationSetter(&config.Auth.JWT.Leeway)},
+		{"AUTH_HMAC_MAX_SKEW", "hmac-max-skew", "how old or early a request signature may be", durationSetter(&config.Auth.HMAC.MaxSkew)},
+		{"RATE_LIMIT_ENABLED", "rate-limit", "limit the request rate of each client", boolSetter(&config.RateLimit.Enabled)},
+		{"METRICS_ENABLED", "metrics", "serve Prometheus metrics at /metrics", boolSetter(&config.Metrics.Enabled)},
+		{"TRACING_EXPORTER", "tracing-exporter", "where spans are exported: none, stdout, file or otlp", stringSetter(&config.Tracing.Exporter)},
+		{"TRACING_FILE", "tracing-file", "file the file exporter appends spans to", stringSetter(&config.Tracing.File)},
+		{"TRACING_OTLP_ENDPOINT", "tracing-otlp-endpoint", "OTLP/HTTP traces URL, e.g. http://localhost:4318/v1/traces", stringSetter(&config.Tracing.OTLPEndpoint)},
+		{"TRACING_SERVICE_NAME", "tracing-service-name", "service.name reported with exported spans", stringSetter(&config.Tracing.ServiceName)},
+		{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of new traces that are kept, 0 to 1", floatSetter(&config.Tracing.SampleRatio)},
+		{"OPENAPI_DOCS", "openapi-docs", "serve the OpenAPI document at /openapi.json and /docs", boolSetter(&config.OpenAPI.Docs)},
+		{"OPENAPI_DOCS_ASSETS_URL", "openapi-docs-assets-url", "URL of the swagger-ui-dist files /docs loads", stringSetter(&config.OpenAPI.DocsAssetsURL)},
+		{"OPENAPI_VALIDATE_REQUESTS", "openapi-validate-requests", "reject requests that do not match the OpenAPI document", boolSetter(&config.OpenAPI.ValidateRequests)},
+		{"OPENAPI_VALIDATE_RESPONSES", "openapi-validate-responses", "log responses that do not match the OpenAPI document", boolSetter(&config.OpenAPI.ValidateResponses)},
+		{"OPENAPI_MAX_BODY_BYTES", "openapi-max-body-bytes", "largest request body validated, larger ones are rejected with 413", intSetter(&config.OpenAPI.MaxBodyBytes)},
+		{"CONCURRENCY_REQUIRE_IF_MATCH", "require-if-match", "reject category updates, moves and deletes without an If-Match header", boolSetter(&config.Concurrency.RequireIfMatch)},
+	}
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, the file named by -config or CONFIG_FILE, environment variables
+// and command-line flags. It returns the arguments left after the flags.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, []string, error) {
+	config := Default()
+	settings := config.settings()
+
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+	var configFile string
+
+	fs := flag.NewFlagSet("golang-restful-api", flag.ContinueOnError)
+	fs.StringVar(&configFile, "config", "", "path to a YAML or JSON config file (env CONFIG_FILE)")
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flag, fmt.Sprintf("%s (env %s)", s.usage, s.env), func(value string) error {
+			flagValues = append(flagValues, flagValue{setting: s, value: value})
+			return nil
+		})
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return config, nil, err
+	}
+
+	if configFile == "" {
+		configFile, _ = lookupEnv("CONFIG_FILE")
+	}
+	if configFile != "" {
+		err = config.loadFile(configFile)
+		if err != nil {
+			return config, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok {
+			err = s.set(value)
+			if err != nil {
+				return config, nil, fmt.Errorf("env %s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, f := range flagValues {
+		err = f.setting.set(f.value)
+		if err != nil {
+			return config, nil, fmt.Errorf("flag -%s: %w", f.setting.flag, err)
+		}
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return config, nil, err
+	}
+	return config, fs.Args(), nil
+}
+
+func (config *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, config)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, config)
+	default:
+		return fmt.Errorf("config file %s must be .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (config Config) Validate() error {
+	var errs []string
+
+	if config.Server.Port < 1 || config.Server.Port > 65535 {
+		errs = append(errs, "server.port must be between 1 and 65535")
+	}
+	if config.Server.ReadTimeout < 0 || config.Server.ReadHeaderTimeout < 0 || config.Server.WriteTimeout < 0 ||
+		config.Server.IdleTimeout < 0 || config.Server.ShutdownTimeout < 0 || config.Server.ShutdownDelay < 0 {
+		errs = append(errs, "server timeouts must not be negative")
+	}
+	if config.Server.ShutdownTimeout > 0 && config.Server.ShutdownDelay >= config.Server.ShutdownTimeout {
+		errs = append(errs, "server.shutdown_delay must be shorter than server.shutdown_timeout, which it counts against")
+	}
+	if config.Server.ReadinessTimeout <= 0 {
+		errs = append(errs, "server.readiness_timeout must be positive")
+	}
+
+	knownDialect := false
+	for _, dialect := range dialects {
+		if strings.EqualFold(config.Database.Dialect, dialect) {
+			knownDialect = true
+		}
+	}
+	if !knownDialect {
+		errs = append(errs, fmt.Sprintf("database.dialect %q is not one of mysql, postgres or sqlite", config.Database.Dialect))
+	}
+	if config.Database.DSN == "" {
+		errs = append(errs, "database.dsn must be set")
+	}
+	if config.Database.MaxOpenConns < 1 {
+		errs = append(errs, "database.max_open_conns must be at least 1")
+	}
+	if config.Database.MaxIdleConns < 0 || config.Database.MaxIdleConns > config.Database.MaxOpenConns {
+		errs = append(errs, "database.max_idle_conns must be between 0 and database.max_open_conns")
+	}
+	if config.Database.ConnMaxIdleTime < 0 || config.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, "database connection timeouts must not be negative")
+	}
+
+	if len(config.Auth.Methods) == 0 {
+		errs = append(errs, "auth.methods must name at least one of api_key, jwt or hmac")
+	}
+	for _, method := range config.Auth.Methods {
+		known := false
+		for _, authMethod := range authMethods {
+			if strings.EqualFold(method, authMethod) {
+				known = true
+			}
+		}
+		if !known {
+			errs = append(errs, fmt.Sprintf("auth.methods: %q is not one of api_key, jwt or hmac", method))
+		}
+	}
+
+	knownKeyStore := false
+	for _, keyStore := range keyStores {
+		if strings.EqualFold(config.Auth.KeyStore, keyStore) {
+			knownKeyStore = true
+		}
+	}
+	if !knownKeyStore {
+		errs = append(errs, fmt.Sprintf("auth.key_store %q is not one of sql or memory", config.Auth.KeyStore))
+	}
+	// A memory key store starts empty, so without auth.api_key no request
+	// could ever authenticate.
+	if config.Auth.HasMethod("api_key") && strings.EqualFold(config.Auth.KeyStore, "memory") && config.Auth.APIKey == "" {
+		errs = append(errs, "auth.api_key must be set when auth.key_store is memory")
+	}
+	if config.Auth.HasMethod("jwt") && config.Auth.JWT.Secret == "" && config.Auth.JWT.JWKSFile == "" {
+		errs = append(errs, "auth.jwt.secret or auth.jwt.jwks_file must be set when auth.methods includes jwt")
+	}
+	if config.Auth.JWT.Leeway < 0 {
+		errs = append(errs, "auth.jwt.leeway must not be negative")
+	}
+	if config.Auth.HasMethod("hmac") && len(config.Auth.HMAC.Keys) == 0 {
+		errs = append(errs, "auth.hmac.keys must be set when auth.methods includes hmac")
+	}
+	for i, key := range config.Auth.HMAC.Keys {
+		if key.Id == "" || key.Secret == "" {
+			errs = append(errs, fmt.Sprintf("auth.hmac.keys[%d] must have an id and a secret", i))
+		}
+	}
+	if config.Auth.HMAC.MaxSkew <= 0 {
+		errs = append(errs, "auth.hmac.max_skew must be positive")
+	}
+
+	for name, group := range config.RateLimit.Groups {
+		if group.Requests < 1 || group.Per <= 0 || group.Burst < 0 {
+			errs = append(errs, fmt.Sprintf("rate_limit.groups.%s needs positive requests and per, and a burst that is not negative", name))
+		}
+	}
+
+	switch strings.ToLower(config.Tracing.Exporter) {
+	case "none", "stdout":
+	case "file":
+		if config.Tracing.File == "" {
+			errs = append(errs, "tracing.file must be set when tracing.exporter is file")
+		}
+	case "otlp":
+		if config.Tracing.OTLPEndpoint == "" {
+			errs = append(errs, "tracing.otlp_endpoint must be set when tracing.exporter is otlp")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("tracing.exporter %q is not one of none, stdout, file or otlp", config.Tracing.Exporter))
+	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		errs = append(errs, "tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if config.OpenAPI.Docs && config.OpenAPI.DocsAssetsURL == "" {
+		errs = append(errs, "openapi.docs_assets_url must be set when openapi.docs is enabled")
+	}
+	if config.OpenAPI.ValidateRequests && config.OpenAPI.MaxBodyBytes < 1 {
+		errs = append(errs, "openapi.max_body_bytes must be positive when openapi.validate_requests is enabled")
+	}
+
+	if len(errs) > 0 {
+		return errors.New("invalid config: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func stringSetter(target *string) func(string) error {
+	return func(value string) error {
+		*target = value
+		return nil
+	}
+}
+
+func listSetter(target *[]string) func(string) error {
+	return func(value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*target = list
+		return nil
+	}
+}
+
+func intSetter(target *int) func(string) error {
+	return func(value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}
+}
+
+func boolSetter(target *bool) func(string) error {
+	return func(value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}
+}
+
+func floatSetter(target *float64) func(string) error {
+	return func(value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+		return nil
+	}
+}
+
+func durationSetter(target *Duration) func(string) error {
+	return func(value string) error {
+		return target.UnmarshalText([]byte(value))
+	}
+}
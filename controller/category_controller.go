@@ -12,4 +12,8 @@ type CategoryController interface {
 	Delete(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
 	FindById(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
 	FindAll(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+	Move(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+	FindChildren(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+	FindAncestors(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
+	FindSubtree(writer http.ResponseWriter, request *http.Request, params httprouter.Params)
 }
@@ -1,31 +1,51 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/exception"
 	"github.com/rtanx/golang-restful-api/helper"
 	webrequest "github.com/rtanx/golang-restful-api/model/web/request"
 	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/protocol"
 	"github.com/rtanx/golang-restful-api/service"
 )
 
+// CategoryControllerImpl sends the version of a category as its ETag. PUT,
+// DELETE and move are conditional on If-Match, which is required when
+// RequireIfMatch is set, and GET of a category answers If-None-Match with
+// 304 Not Modified.
 type CategoryControllerImpl struct {
 	CategoryService service.CategoryService
+	RequireIfMatch  bool
 }
 
-func NewCategoryController(categoryService service.CategoryService) CategoryController {
+func NewCategoryController(categoryService service.CategoryService, concurrency config.ConcurrencyConfig) CategoryController {
 	return &CategoryControllerImpl{
 		CategoryService: categoryService,
+		RequireIfMatch:  concurrency.RequireIfMatch,
 	}
 }
 
 func (controller *CategoryControllerImpl) Create(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 	categoryCreateRequest := webrequest.CategoryCreateRequest{}
-	helper.ReadFromRequestBody(request, &categoryCreateRequest)
+	err := readBody(request, &categoryCreateRequest)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
 
-	categoryResponse := controller.CategoryService.Create(request.Context(), categoryCreateRequest)
+	categoryResponse, err := controller.CategoryService.Create(request.Context(), categoryCreateRequest)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	writer.Header().Set("ETag", protocol.ETag(categoryResponse.Version))
 	webResponse := webresponse.WebResponse{
 		Code:   200,
 		Status: "OK",
@@ -36,14 +56,31 @@ func (controller *CategoryControllerImpl) Create(writer http.ResponseWriter, req
 
 func (controller *CategoryControllerImpl) Update(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 	categoryUpdateRequest := webrequest.CategoryUpdateRequest{}
-	helper.ReadFromRequestBody(request, &categoryUpdateRequest)
+	err := readBody(request, &categoryUpdateRequest)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
 
-	id, err := strconv.ParseInt(params.ByName("categoryId"), 10, 64)
-	helper.PanicfIfErr(err)
+	id, err := readCategoryId(params)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
 
 	categoryUpdateRequest.Id = id
+	categoryUpdateRequest.IfMatch, err = controller.ifMatch(request)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
 
-	categoryResponse := controller.CategoryService.Update(request.Context(), categoryUpdateRequest)
+	categoryResponse, err := controller.CategoryService.Update(request.Context(), categoryUpdateRequest)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	writer.Header().Set("ETag", protocol.ETag(categoryResponse.Version))
 	webResponse := webresponse.WebResponse{
 		Code:   200,
 		Status: "OK",
@@ -54,10 +91,23 @@ func (controller *CategoryControllerImpl) Update(writer http.ResponseWriter, req
 }
 
 func (controller *CategoryControllerImpl) Delete(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-	categoryId, err := strconv.ParseInt(params.ByName("categoryId"), 10, 64)
-	helper.PanicfIfErr(err)
+	categoryId, err := readCategoryId(params)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+
+	ifMatch, err := controller.ifMatch(request)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
 
-	controller.CategoryService.Delete(request.Context(), categoryId)
+	err = controller.CategoryService.Delete(request.Context(), webrequest.CategoryDeleteRequest{Id: categoryId, IfMatch: ifMatch})
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
 	webResponse := webresponse.WebResponse{
 		Code:   200,
 		Status: "OK",
@@ -67,9 +117,21 @@ func (controller *CategoryControllerImpl) Delete(writer http.ResponseWriter, req
 }
 
 func (controller *CategoryControllerImpl) FindById(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-	categoryId, err := strconv.ParseInt(params.ByName("categoryId"), 10, 64)
-	helper.PanicfIfErr(err)
-	categoryResponse := controller.CategoryService.FindById(request.Context(), categoryId)
+	categoryId, err := readCategoryId(params)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	categoryResponse, err := controller.CategoryService.FindById(request.Context(), categoryId)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	writer.Header().Set("ETag", protocol.ETag(categoryResponse.Version))
+	if notModified(request, categoryResponse.Version) {
+		writer.WriteHeader(http.StatusNotModified)
+		return
+	}
 	webResponse := webresponse.WebResponse{
 		Code:   200,
 		Status: "OK",
@@ -80,7 +142,102 @@ func (controller *CategoryControllerImpl) FindById(writer http.ResponseWriter, r
 }
 
 func (controller *CategoryControllerImpl) FindAll(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-	categoriesResponse := controller.CategoryService.FindAll(request.Context())
+	categoryListRequest, err := readCategoryListRequest(request)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+
+	categoryListResponse, err := controller.CategoryService.FindAll(request.Context(), categoryListRequest)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	webResponse := webresponse.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   categoryListResponse.Categories,
+		Meta:   categoryListResponse.Meta,
+	}
+	helper.WriteToResponseBody(writer, webResponse)
+
+}
+
+func (controller *CategoryControllerImpl) Move(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	categoryMoveRequest := webrequest.CategoryMoveRequest{}
+	err := readBody(request, &categoryMoveRequest)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+
+	id, err := readCategoryId(params)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+
+	categoryMoveRequest.Id = id
+	categoryMoveRequest.IfMatch, err = controller.ifMatch(request)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+
+	categoryResponse, err := controller.CategoryService.Move(request.Context(), categoryMoveRequest)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	writer.Header().Set("ETag", protocol.ETag(categoryResponse.Version))
+	webResponse := webresponse.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   categoryResponse,
+	}
+	helper.WriteToResponseBody(writer, webResponse)
+
+}
+
+func (controller *CategoryControllerImpl) FindChildren(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	categoryId, err := readCategoryId(params)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+
+	categoryListRequest, err := readCategoryListRequest(request)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+
+	categoryListResponse, err := controller.CategoryService.FindChildren(request.Context(), categoryId, categoryListRequest)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	webResponse := webresponse.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   categoryListResponse.Categories,
+		Meta:   categoryListResponse.Meta,
+	}
+	helper.WriteToResponseBody(writer, webResponse)
+
+}
+
+func (controller *CategoryControllerImpl) FindAncestors(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	categoryId, err := readCategoryId(params)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	categoriesResponse, err := controller.CategoryService.FindAncestors(request.Context(), categoryId)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
 	webResponse := webresponse.WebResponse{
 		Code:   200,
 		Status: "OK",
@@ -89,3 +246,105 @@ func (controller *CategoryControllerImpl) FindAll(writer http.ResponseWriter, re
 	helper.WriteToResponseBody(writer, webResponse)
 
 }
+
+func (controller *CategoryControllerImpl) FindSubtree(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	categoryId, err := readCategoryId(params)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	categoryTreeResponse, err := controller.CategoryService.FindSubtree(request.Context(), categoryId)
+	if err != nil {
+		exception.ErrorHandler(writer, request, err)
+		return
+	}
+	webResponse := webresponse.WebResponse{
+		Code:   200,
+		Status: "OK",
+		Data:   categoryTreeResponse,
+	}
+	helper.WriteToResponseBody(writer, webResponse)
+
+}
+
+func readCategoryListRequest(request *http.Request) (webrequest.CategoryListRequest, error) {
+	query := request.URL.Query()
+	categoryListRequest := webrequest.CategoryListRequest{
+		Sort:      query.Get("sort"),
+		Name:      query.Get("name"),
+		NameMatch: query.Get("name_match"),
+		Cursor:    query.Get("cursor"),
+	}
+
+	intParams := map[string]*int{
+		"page":   &categoryListRequest.Page,
+		"size":   &categoryListRequest.Size,
+		"limit":  &categoryListRequest.Limit,
+		"offset": &categoryListRequest.Offset,
+	}
+	for name, target := range intParams {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return categoryListRequest, exception.NewBadRequestError(fmt.Sprintf("query parameter %s must be an integer", name))
+		}
+		*target = parsed
+	}
+	return categoryListRequest, nil
+}
+
+// ifMatch returns the versions If-Match allows, or none for "*" and, unless
+// RequireIfMatch is set, for a request without the header.
+func (controller *CategoryControllerImpl) ifMatch(request *http.Request) ([]int64, error) {
+	header := strings.Join(request.Header.Values("If-Match"), ",")
+	if strings.TrimSpace(header) == "" {
+		if controller.RequireIfMatch {
+			return nil, exception.NewPreconditionRequiredError("If-Match header with the ETag of the category is required")
+		}
+		return nil, nil
+	}
+	versions, any := protocol.ParseETags(header, false)
+	if any {
+		return nil, nil
+	}
+	return versions, nil
+}
+
+func notModified(request *http.Request, version int64) bool {
+	header := strings.Join(request.Header.Values("If-None-Match"), ",")
+	if strings.TrimSpace(header) == "" {
+		return false
+	}
+	versions, any := protocol.ParseETags(header, true)
+	if any {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody and readCategoryId report malformed input as
+// exception.BadRequestError, which ErrorHandler would otherwise answer with
+// 500.
+func readBody(request *http.Request, result interface{}) error {
+	err := helper.ReadFromRequestBody(request, result)
+	if err != nil {
+		return exception.NewBadRequestError("invalid JSON body")
+	}
+	return nil
+}
+
+func readCategoryId(params httprouter.Params) (int64, error) {
+	categoryId, err := strconv.ParseInt(params.ByName("categoryId"), 10, 64)
+	if err != nil {
+		return 0, exception.NewBadRequestError("categoryId must be an integer")
+	}
+	return categoryId, nil
+}
@@ -4,17 +4,18 @@ import (
 	"database/sql"
 	"time"
 
+	"github.com/rtanx/golang-restful-api/config"
 	"github.com/rtanx/golang-restful-api/helper"
 )
 
-func NewDB() *sql.DB {
-	db, err := sql.Open("mysql", "root:root@tcp(localhost:3306)/learn_golang_restful_api")
+func NewDB(dialect Dialect, config config.DatabaseConfig) *sql.DB {
+	db, err := sql.Open(dialect.DriverName(), config.DSN)
 	helper.PanicfIfErr(err)
 
-	db.SetMaxIdleConns(5)
-	db.SetMaxOpenConns(20)
-	db.SetConnMaxIdleTime(60 * time.Minute)
-	db.SetConnMaxLifetime(10 * time.Minute)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetConnMaxIdleTime(time.Duration(config.ConnMaxIdleTime))
+	db.SetConnMaxLifetime(time.Duration(config.ConnMaxLifetime))
 
 	return db
 }
@@ -0,0 +1,301 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/db"
+	"github.com/rtanx/golang-restful-api/helper"
+)
+
+// Migration files live in sql/<dialect>/ and are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. Statements inside a
+// file are separated by a semicolon at the end of a line. The first migration
+// creates the category table only if it does not exist, so that databases
+// set up by hand before migrations existed can be migrated too.
+//
+//go:embed sql
+var files embed.FS
+
+var ErrNoMigration = errors.New("no migration to roll back")
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	DB         *sql.DB
+	Dialect    db.Dialect
+	Migrations []Migration
+}
+
+func NewMigrator(DB *sql.DB, dialect db.Dialect) (*Migrator, error) {
+	sub, err := fs.Sub(files, path.Join("sql", dialect.Name()))
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(sub)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		DB:         DB,
+		Dialect:    dialect,
+		Migrations: migrations,
+	}, nil
+}
+
+// Load reads the migrations found at the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		version, name, direction, err := parseFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func parseFileName(fileName string) (version int64, name string, direction string, err error) {
+	base := strings.TrimSuffix(fileName, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", fmt.Errorf("migration file %q must end with .up.sql or .down.sql", fileName)
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	parts := strings.SplitN(base, "_", 2)
+	if len(parts) != 2 {
+		return 0, "", "", fmt.Errorf("migration file %q must be named <version>_<name>", fileName)
+	}
+	version, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("migration file %q has an invalid version: %w", fileName, err)
+	}
+	return version, parts[1], direction, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func (migrator *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := migrator.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range migrator.Migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = migrator.apply(ctx, migration, true)
+		if err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the most recently applied migration.
+func (migrator *Migrator) Down(ctx context.Context) (Migration, error) {
+	migration, err := migrator.latest(ctx)
+	if err != nil {
+		return migration, err
+	}
+	return migration, migrator.apply(ctx, migration, false)
+}
+
+// Redo rolls back the most recently applied migration and applies it again.
+func (migrator *Migrator) Redo(ctx context.Context) (Migration, error) {
+	migration, err := migrator.Down(ctx)
+	if err != nil {
+		return migration, err
+	}
+	return migration, migrator.apply(ctx, migration, true)
+}
+
+func (migrator *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := migrator.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range migrator.Migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Pending returns how many known migrations have not been applied yet.
+func (migrator *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (migrator *Migrator) latest(ctx context.Context) (Migration, error) {
+	applied, err := migrator.appliedVersions(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	for i := len(migrator.Migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[migrator.Migrations[i].Version]; ok {
+			return migrator.Migrations[i], nil
+		}
+	}
+	return Migration{}, ErrNoMigration
+}
+
+func (migrator *Migrator) apply(ctx context.Context, migration Migration, up bool) (err error) {
+	script := migration.Up
+	if !up {
+		script = migration.Down
+		if script == "" {
+			return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+	}
+
+	tx, err := migrator.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer helper.CommitOrRollback(tx, &err)
+
+	for _, statement := range splitStatements(script) {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if up {
+		SQL := "INSERT INTO schema_migrations(version, name, applied_at) VALUES (?, ?, ?)"
+		_, err = tx.ExecContext(ctx, migrator.Dialect.Rebind(SQL), migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		SQL := "DELETE FROM schema_migrations WHERE version = ?"
+		_, err = tx.ExecContext(ctx, migrator.Dialect.Rebind(SQL), migration.Version)
+	}
+	return err
+}
+
+func (migrator *Migrator) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	SQL := `CREATE TABLE IF NOT EXISTS schema_migrations(
+    version BIGINT NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`
+	_, err := migrator.DB.ExecContext(ctx, SQL)
+	if err != nil {
+		return nil, err
+	}
+
+	resRows, err := migrator.DB.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer resRows.Close()
+
+	applied := map[int64]time.Time{}
+	for resRows.Next() {
+		var version int64
+		var appliedAt time.Time
+		err = resRows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, resRows.Err()
+}
+
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			if statement := strings.TrimSpace(current.String()); statement != ";" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+		}
+	}
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
@@ -1,4 +1,4 @@
-CREATE TABLE category(
+CREATE TABLE IF NOT EXISTS category(
     id INTEGER PRIMARY KEY auto_increment,
     name VARCHAR(200) NOT NULL
-) engine = InnoDB;
\ No newline at end of file
+) engine = InnoDB;
@@ -0,0 +1,2 @@
+ALTER TABLE category DROP FOREIGN KEY category_parent_id_fk;
+ALTER TABLE category DROP COLUMN parent_id;
@@ -0,0 +1,3 @@
+ALTER TABLE category
+    ADD COLUMN parent_id INTEGER NULL,
+    ADD CONSTRAINT category_parent_id_fk FOREIGN KEY (parent_id) REFERENCES category(id);
@@ -0,0 +1,3 @@
+ALTER TABLE category
+    DROP INDEX category_name_uq,
+    DROP COLUMN name_lower;
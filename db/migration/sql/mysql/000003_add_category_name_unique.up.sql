@@ -0,0 +1,6 @@
+-- Fails when case-insensitive duplicate names already exist; rename or merge
+-- them before applying. The index is on a generated column rather than on
+-- (LOWER(name)) since functional indexes need MySQL 8.0.13.
+ALTER TABLE category
+    ADD COLUMN name_lower VARCHAR(200) AS (LOWER(name)) STORED,
+    ADD UNIQUE INDEX category_name_uq (name_lower);
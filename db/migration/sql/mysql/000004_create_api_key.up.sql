@@ -0,0 +1,10 @@
+CREATE TABLE api_key(
+    id INTEGER PRIMARY KEY auto_increment,
+    name VARCHAR(200) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    expires_at DATETIME(6) NULL,
+    revoked_at DATETIME(6) NULL,
+    last_used_at DATETIME(6) NULL,
+    UNIQUE KEY api_key_hash_uq (key_hash)
+) engine = InnoDB;
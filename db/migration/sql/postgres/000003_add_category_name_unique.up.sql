@@ -0,0 +1,3 @@
+-- Fails when case-insensitive duplicate names already exist; rename or merge
+-- them before applying.
+CREATE UNIQUE INDEX category_name_uq ON category (LOWER(name));
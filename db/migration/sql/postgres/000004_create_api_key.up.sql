@@ -0,0 +1,10 @@
+CREATE TABLE api_key(
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL
+);
+CREATE UNIQUE INDEX api_key_hash_uq ON api_key(key_hash);
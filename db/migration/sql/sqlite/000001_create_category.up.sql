@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS category(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(200) NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP INDEX category_parent_id_idx;
+ALTER TABLE category DROP COLUMN parent_id;
@@ -0,0 +1,2 @@
+ALTER TABLE category ADD COLUMN parent_id INTEGER NULL REFERENCES category(id);
+CREATE INDEX category_parent_id_idx ON category(parent_id);
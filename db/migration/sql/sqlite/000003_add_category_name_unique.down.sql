@@ -0,0 +1 @@
+DROP INDEX category_name_uq;
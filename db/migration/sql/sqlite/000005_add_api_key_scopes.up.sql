@@ -0,0 +1,3 @@
+ALTER TABLE api_key ADD COLUMN scopes VARCHAR(500) NOT NULL DEFAULT '';
+-- Keys created before scopes existed keep the access they had.
+UPDATE api_key SET scopes = 'categories:read categories:write categories:delete';
@@ -0,0 +1 @@
+ALTER TABLE category ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,21 @@
+package exception
+
+import webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+
+// BadRequestError optionally lists the fields that made the request invalid.
+type BadRequestError struct {
+	Message string
+	Fields  []webresponse.ProblemFieldError
+}
+
+func NewBadRequestError(message string) BadRequestError {
+	return BadRequestError{Message: message}
+}
+
+func NewBadRequestFieldsError(message string, fields []webresponse.ProblemFieldError) BadRequestError {
+	return BadRequestError{Message: message, Fields: fields}
+}
+
+func (e BadRequestError) Error() string {
+	return e.Message
+}
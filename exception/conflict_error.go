@@ -0,0 +1,22 @@
+package exception
+
+type ConflictError struct {
+	Message string
+	Err     error
+}
+
+func NewConflictError(message string) ConflictError {
+	return ConflictError{Message: message}
+}
+
+func WrapConflictError(message string, err error) ConflictError {
+	return ConflictError{Message: message, Err: err}
+}
+
+func (e ConflictError) Error() string {
+	return e.Message
+}
+
+func (e ConflictError) Unwrap() error {
+	return e.Err
+}
@@ -1,63 +1,230 @@
 package exception
 
 import (
+	"errors"
+	"log"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/i18n"
+	"github.com/rtanx/golang-restful-api/metrics"
 	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/protocol"
 )
 
+const ProblemContentType = protocol.ProblemContentType
+
+// ErrorHandler writes the response for err. Controllers call it directly for
+// errors returned by the service layer; it is also installed as the router's
+// PanicHandler so that an unexpected panic still produces a response.
+//
+// Errors are written in the WebResponse envelope unless the request prefers
+// application/problem+json, in which case an RFC 7807 problem is written.
 func ErrorHandler(w http.ResponseWriter, r *http.Request, err interface{}) {
-	switch e := err.(type) {
-	case NotFoundError:
-		notFoundError(w, r, e)
-	case validator.ValidationErrors:
-		validationErrors(w, r, e)
-	default:
-		internalServerError(w, r, e)
+	if e, ok := err.(error); ok {
+		var notFound NotFoundError
+		var badRequest BadRequestError
+		var conflict ConflictError
+		var forbidden ForbiddenError
+		var tooManyRequests TooManyRequestsError
+		var preconditionFailed PreconditionFailedError
+		var preconditionRequired PreconditionRequiredError
+		var payloadTooLarge PayloadTooLargeError
+		var validationErrs validator.ValidationErrors
+
+		switch {
+		case errors.As(e, &notFound):
+			notFoundError(w, r, notFound)
+			return
+		case errors.As(e, &validationErrs):
+			validationErrors(w, r, validationErrs)
+			return
+		case errors.As(e, &badRequest):
+			badRequestError(w, r, badRequest)
+			return
+		case errors.As(e, &conflict):
+			conflictError(w, r, conflict)
+			return
+		case errors.As(e, &forbidden):
+			forbiddenError(w, r, forbidden)
+			return
+		case errors.As(e, &tooManyRequests):
+			tooManyRequestsError(w, r, tooManyRequests)
+			return
+		case errors.As(e, &preconditionFailed):
+			preconditionFailedError(w, r, preconditionFailed)
+			return
+		case errors.As(e, &preconditionRequired):
+			preconditionRequiredError(w, r, preconditionRequired)
+			return
+		case errors.As(e, &payloadTooLarge):
+			payloadTooLargeError(w, r, payloadTooLarge)
+			return
+		}
 	}
+	internalServerError(w, r, err)
 }
 
+// validationErrors translates each field error with the translator the
+// LocaleMiddleware stored in the request context. Without one the data keeps
+// the validator's untranslated message.
 func validationErrors(w http.ResponseWriter, r *http.Request, err validator.ValidationErrors) {
-	status := http.StatusBadRequest
+	metrics.ObserveError(r.Context(), "validation")
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	trans, translated := i18n.FromContext(r.Context())
 
-	resp := webresponse.WebResponse{
-		Code:   status,
-		Status: "Bad Request",
-		Data:   err.Error(),
+	var fields []webresponse.ProblemFieldError
+	messages := map[string]string{}
+	for _, fieldErr := range err {
+		field := webresponse.ProblemFieldError{
+			Field: fieldPath(fieldErr),
+			Rule:  fieldErr.Tag(),
+			Param: fieldErr.Param(),
+		}
+		if translated {
+			field.Message = fieldErr.Translate(trans)
+			messages[field.Field] = field.Message
+		}
+		fields = append(fields, field)
 	}
-	helper.WriteToResponseBody(w, resp)
+
+	var data interface{} = err.Error()
+	if translated {
+		data = messages
+	}
+	writeError(w, r, http.StatusBadRequest, "Bad Request", data, "request validation failed", fields)
+}
+
+// badRequestError writes a map of field to message as the data of errors
+// that list their fields, like validationErrors does.
+func badRequestError(w http.ResponseWriter, r *http.Request, err BadRequestError) {
+	metrics.ObserveError(r.Context(), "bad_request")
+
+	var data interface{} = err.Error()
+	if len(err.Fields) > 0 {
+		messages := map[string]string{}
+		for _, field := range err.Fields {
+			messages[field.Field] = field.Message
+		}
+		data = messages
+	}
+	writeError(w, r, http.StatusBadRequest, "Bad Request", data, err.Error(), err.Fields)
 }
 
 func notFoundError(w http.ResponseWriter, r *http.Request, err NotFoundError) {
+	metrics.ObserveError(r.Context(), "not_found")
+	writeError(w, r, http.StatusNotFound, "Not Found", err.Error(), err.Error(), nil)
+}
 
-	status := http.StatusNotFound
+func conflictError(w http.ResponseWriter, r *http.Request, err ConflictError) {
+	metrics.ObserveError(r.Context(), "conflict")
+	writeError(w, r, http.StatusConflict, "Conflict", err.Error(), err.Error(), nil)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+func forbiddenError(w http.ResponseWriter, r *http.Request, err ForbiddenError) {
+	metrics.ObserveError(r.Context(), "forbidden")
+	writeError(w, r, http.StatusForbidden, "Forbidden", err.Error(), err.Error(), nil)
+}
 
-	resp := webresponse.WebResponse{
-		Code:   status,
-		Status: "Not Found",
-		Data:   err.Error,
-	}
-	helper.WriteToResponseBody(w, resp)
+func tooManyRequestsError(w http.ResponseWriter, r *http.Request, err TooManyRequestsError) {
+	metrics.ObserveError(r.Context(), "too_many_requests")
+	writeError(w, r, http.StatusTooManyRequests, "Too Many Requests", err.Error(), err.Error(), nil)
+}
+
+func preconditionFailedError(w http.ResponseWriter, r *http.Request, err PreconditionFailedError) {
+	metrics.ObserveError(r.Context(), "precondition_failed")
+	writeError(w, r, http.StatusPreconditionFailed, "Precondition Failed", err.Error(), err.Error(), nil)
+}
+
+func preconditionRequiredError(w http.ResponseWriter, r *http.Request, err PreconditionRequiredError) {
+	metrics.ObserveError(r.Context(), "precondition_required")
+	writeError(w, r, http.StatusPreconditionRequired, "Precondition Required", err.Error(), err.Error(), nil)
 }
 
+func payloadTooLargeError(w http.ResponseWriter, r *http.Request, err PayloadTooLargeError) {
+	metrics.ObserveError(r.Context(), "payload_too_large")
+	writeError(w, r, http.StatusRequestEntityTooLarge, "Request Entity Too Large", err.Error(), err.Error(), nil)
+}
+
+// internalServerError logs err instead of returning it, since it may carry
+// driver messages that reveal the schema.
 func internalServerError(w http.ResponseWriter, r *http.Request, err interface{}) {
-	status := http.StatusInternalServerError
+	metrics.ObserveError(r.Context(), "internal")
+
+	// The request id is read from the response, where the RequestID
+	// middleware puts it, as this package cannot import middleware.
+	if id := w.Header().Get("X-Request-ID"); id != "" {
+		log.Printf("%s %s [%s]: %v", r.Method, r.URL.Path, id, err)
+	} else {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+
+	writeError(w, r, http.StatusInternalServerError, "Internal Server Error", nil, "", nil)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, statusText string, data interface{}, detail string, fields []webresponse.ProblemFieldError) {
+	if PrefersProblem(r) {
+		w.Header().Set("Content-Type", ProblemContentType)
+		w.WriteHeader(status)
+
+		resp := webresponse.ProblemResponse{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   detail,
+			Instance: r.URL.RequestURI(),
+			Errors:   fields,
+		}
+		helper.WriteToResponseBody(w, resp)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
 	resp := webresponse.WebResponse{
 		Code:   status,
-		Status: "Internal Server Error",
-		Data:   err,
+		Status: statusText,
+		Data:   data,
 	}
 	helper.WriteToResponseBody(w, resp)
 }
+
+// PrefersProblem reports whether the Accept header ranks
+// application/problem+json at least as high as application/json.
+func PrefersProblem(r *http.Request) bool {
+	problem, plain := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case ProblemContentType:
+			problem = q
+		case "application/json":
+			plain = q
+		}
+	}
+	return problem > 0 && problem >= plain
+}
+
+// fieldPath returns the JSON path of the failing field, e.g. "name", without
+// the name of the validated struct.
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fieldErr.Field()
+}
@@ -1,9 +1,22 @@
 package exception
 
 type NotFoundError struct {
-	Error string
+	Message string
+	Err     error
 }
 
-func NewNotFoundError(err string) NotFoundError {
-	return NotFoundError{Error: err}
+func NewNotFoundError(message string) NotFoundError {
+	return NotFoundError{Message: message}
+}
+
+func WrapNotFoundError(err error) NotFoundError {
+	return NotFoundError{Message: err.Error(), Err: err}
+}
+
+func (e NotFoundError) Error() string {
+	return e.Message
+}
+
+func (e NotFoundError) Unwrap() error {
+	return e.Err
 }
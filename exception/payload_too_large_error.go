@@ -0,0 +1,15 @@
+package exception
+
+// PayloadTooLargeError is returned when a request body is longer than the
+// server accepts.
+type PayloadTooLargeError struct {
+	Message string
+}
+
+func NewPayloadTooLargeError(message string) PayloadTooLargeError {
+	return PayloadTooLargeError{Message: message}
+}
+
+func (e PayloadTooLargeError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,38 @@
+package exception
+
+// PreconditionFailedError is returned when the If-Match header of a request
+// does not match the current version of the resource.
+type PreconditionFailedError struct {
+	Message string
+	Err     error
+}
+
+func NewPreconditionFailedError(message string) PreconditionFailedError {
+	return PreconditionFailedError{Message: message}
+}
+
+func WrapPreconditionFailedError(message string, err error) PreconditionFailedError {
+	return PreconditionFailedError{Message: message, Err: err}
+}
+
+func (e PreconditionFailedError) Error() string {
+	return e.Message
+}
+
+func (e PreconditionFailedError) Unwrap() error {
+	return e.Err
+}
+
+// PreconditionRequiredError is returned when a request that must be
+// conditional has no If-Match header.
+type PreconditionRequiredError struct {
+	Message string
+}
+
+func NewPreconditionRequiredError(message string) PreconditionRequiredError {
+	return PreconditionRequiredError{Message: message}
+}
+
+func (e PreconditionRequiredError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,28 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/rtanx/golang-restful-api/db/migration"
+)
+
+func DatabaseCheck(DB *sql.DB) Check {
+	return DB.PingContext
+}
+
+// MigrationsCheck fails while migrations embedded in the binary have not been
+// applied, as the code may rely on the schema they create.
+func MigrationsCheck(migrator *migration.Migrator) Check {
+	return func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d pending migrations", pending)
+		}
+		return nil
+	}
+}
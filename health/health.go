@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/helper"
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+)
+
+const (
+	StatusUp   = "UP"
+	StatusDown = "DOWN"
+)
+
+var ErrShuttingDown = errors.New("shutting down")
+
+// Check reports whether a dependency is usable. It should return once ctx
+// is done.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks registered by the application's
+// components. Every check gets Timeout to finish.
+type Checker struct {
+	Timeout time.Duration
+
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown int32
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	checker := &Checker{Timeout: timeout}
+	checker.Register("shutdown", func(ctx context.Context) error {
+		if atomic.LoadInt32(&checker.shuttingDown) == 1 {
+			return ErrShuttingDown
+		}
+		return nil
+	})
+	return checker
+}
+
+func (checker *Checker) Register(name string, check Check) {
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+
+	checker.checks = append(checker.checks, namedCheck{name: name, check: check})
+}
+
+// ShuttingDown makes the service report not ready, so that it is taken out
+// of rotation while in-flight requests drain.
+func (checker *Checker) ShuttingDown() {
+	atomic.StoreInt32(&checker.shuttingDown, 1)
+}
+
+// Run runs all checks concurrently and reports whether they all passed.
+func (checker *Checker) Run(ctx context.Context) (webresponse.HealthResponse, bool) {
+	checker.mu.RLock()
+	checks := append([]namedCheck(nil), checker.checks...)
+	checker.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checker.Timeout)
+	defer cancel()
+
+	results := make([]webresponse.HealthCheckResponse, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c.check)
+		}(i, c)
+	}
+	wg.Wait()
+
+	resp := webresponse.HealthResponse{Status: StatusUp, Checks: map[string]webresponse.HealthCheckResponse{}}
+	for i, c := range checks {
+		resp.Checks[c.name] = results[i]
+		if results[i].Status != StatusUp {
+			resp.Status = StatusDown
+		}
+	}
+	return resp, resp.Status == StatusUp
+}
+
+// runCheck returns when the check does or when ctx is done, whichever comes
+// first, so that a check ignoring ctx cannot hold up the probe.
+func runCheck(ctx context.Context, check Check) webresponse.HealthCheckResponse {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := webresponse.HealthCheckResponse{Status: StatusUp, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler serves /healthz. It only shows that the process is able to
+// serve requests.
+func (checker *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, webresponse.HealthResponse{Status: StatusUp})
+	})
+}
+
+// ReadinessHandler serves /readyz, responding 503 with the failing checks
+// when the service should not receive traffic.
+func (checker *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ready := checker.Run(r.Context())
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeHealth(w, status, resp)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, status int, resp webresponse.HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	helper.WriteToResponseBody(w, webresponse.WebResponse{
+		Code:   status,
+		Status: http.StatusText(status),
+		Data:   resp,
+	})
+}
@@ -3,12 +3,18 @@ package helper
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/rtanx/golang-restful-api/tracing"
 )
 
-func ReadFromRequestBody(request *http.Request, result interface{}) {
+func ReadFromRequestBody(request *http.Request, result interface{}) error {
+	_, span := tracing.Start(request.Context(), "json.decode")
+	defer span.End()
+
 	decoder := json.NewDecoder(request.Body)
 	err := decoder.Decode(result)
-	PanicfIfErr(err)
+	tracing.RecordError(span, err)
+	return err
 }
 
 func WriteToResponseBody(writer http.ResponseWriter, response interface{}) {
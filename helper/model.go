@@ -7,8 +7,10 @@ import (
 
 func ToCategoryResponse(category domain.Category) webresponse.CategoryResponse {
 	return webresponse.CategoryResponse{
-		Id:   category.Id,
-		Name: category.Name,
+		Id:       category.Id,
+		Name:     category.Name,
+		ParentId: category.ParentId,
+		Version:  category.Version,
 	}
 }
 
@@ -19,3 +21,33 @@ func ToCategoriesResponse(categories []domain.Category) []webresponse.CategoryRe
 	}
 	return categoriesResponse
 }
+
+// ToCategoryTreeResponse nests categories under their parents, using the
+// first category as the root of the tree.
+func ToCategoryTreeResponse(categories []domain.Category) webresponse.CategoryTreeResponse {
+	if len(categories) == 0 {
+		return webresponse.CategoryTreeResponse{}
+	}
+
+	children := map[int64][]domain.Category{}
+	for _, category := range categories[1:] {
+		if category.ParentId != nil {
+			children[*category.ParentId] = append(children[*category.ParentId], category)
+		}
+	}
+
+	var build func(category domain.Category) webresponse.CategoryTreeResponse
+	build = func(category domain.Category) webresponse.CategoryTreeResponse {
+		node := webresponse.CategoryTreeResponse{
+			Id:       category.Id,
+			Name:     category.Name,
+			ParentId: category.ParentId,
+			Children: []webresponse.CategoryTreeResponse{},
+		}
+		for _, child := range children[category.Id] {
+			node.Children = append(node.Children, build(child))
+		}
+		return node
+	}
+	return build(categories[0])
+}
@@ -1,17 +1,22 @@
 package helper
 
-import (
-	"database/sql"
-)
+type Transaction interface {
+	Commit() error
+	Rollback() error
+}
 
-func CommitOrRollback(tx *sql.Tx) {
-	err := recover()
-	if err != nil {
-		errRollback := tx.Rollback()
-		PanicfIfErr(errRollback)
-		panic(err)
-	} else {
-		errCommit := tx.Commit()
-		PanicfIfErr(errCommit)
+// CommitOrRollback must be deferred right after the transaction is started,
+// with a pointer to the caller's named error result. The transaction is
+// committed when the caller returns a nil error and rolled back otherwise.
+// A panic still rolls the transaction back before being re-raised.
+func CommitOrRollback(tx Transaction, err *error) {
+	if p := recover(); p != nil {
+		tx.Rollback()
+		panic(p)
+	}
+	if *err != nil {
+		tx.Rollback()
+		return
 	}
+	*err = tx.Commit()
 }
@@ -0,0 +1,69 @@
+package i18n
+
+import (
+	"context"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	idtranslations "github.com/go-playground/validator/v10/translations/id"
+	"golang.org/x/text/language"
+)
+
+const DefaultLocale = "en"
+
+// Translator selects the validation message translations registered on a
+// validator from a request's Accept-Language header. Validation errors can
+// only be translated by the Translator built for the validator that
+// produced them.
+type Translator struct {
+	Universal *ut.UniversalTranslator
+}
+
+func NewTranslator(validate *validator.Validate) (*Translator, error) {
+	english := en.New()
+	universal := ut.New(english, english, id.New())
+
+	enTrans, _ := universal.GetTranslator("en")
+	err := entranslations.RegisterDefaultTranslations(validate, enTrans)
+	if err != nil {
+		return nil, err
+	}
+	idTrans, _ := universal.GetTranslator("id")
+	err = idtranslations.RegisterDefaultTranslations(validate, idTrans)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Translator{Universal: universal}, nil
+}
+
+// Match returns the best registered translation for an Accept-Language
+// header value, falling back to English.
+func (translator *Translator) Match(acceptLanguage string) ut.Translator {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+
+	var locales []string
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		locales = append(locales, base.String())
+	}
+	trans, found := translator.Universal.FindTranslator(locales...)
+	if !found {
+		trans, _ = translator.Universal.GetTranslator(DefaultLocale)
+	}
+	return trans
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, trans ut.Translator) context.Context {
+	return context.WithValue(ctx, contextKey{}, trans)
+}
+
+func FromContext(ctx context.Context) (ut.Translator, bool) {
+	trans, ok := ctx.Value(contextKey{}).(ut.Translator)
+	return trans, ok
+}
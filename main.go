@@ -1,41 +1,328 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/go-playground/validator/v10"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/config"
 	"github.com/rtanx/golang-restful-api/controller"
 	"github.com/rtanx/golang-restful-api/db"
+	"github.com/rtanx/golang-restful-api/db/migration"
+	"github.com/rtanx/golang-restful-api/health"
 	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/i18n"
+	"github.com/rtanx/golang-restful-api/metrics"
 	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/rtanx/golang-restful-api/openapi"
+	"github.com/rtanx/golang-restful-api/ratelimit"
 	"github.com/rtanx/golang-restful-api/repository"
 	"github.com/rtanx/golang-restful-api/service"
+	"github.com/rtanx/golang-restful-api/tracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
-const HOST = "localhost"
-const PORT = 8080
-
+// main serves the API, or with "migrate up|down|redo|status" manages the
+// schema and with "apikey create|list|revoke" manages API keys, and exits.
+// The server shuts down gracefully on SIGINT or SIGTERM. See config.Load for
+// the available settings.
 func main() {
-	log.Printf("Starting Application on port :%d", PORT)
+	cfg, args, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dialect, err := db.NewDialect(cfg.Database.Dialect)
+	helper.PanicfIfErr(err)
+
+	DB := db.NewDB(dialect, cfg.Database)
 
-	DB := db.NewDB()
-	validate := validator.New()
-	categoryRepository := repository.NewCategoryRepository()
-	categoryService := service.NewCategoryService(categoryRepository, DB, validate)
-	categoryController := controller.NewCategoryController(categoryService)
+	if len(args) > 0 && args[0] == "migrate" {
+		command := "up"
+		if len(args) > 1 {
+			command = args[1]
+		}
+		err = runMigration(DB, dialect, command)
+		DB.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "apikey" {
+		err = runAPIKey(auth.NewSQLKeyStore(DB, dialect), args[1:])
+		DB.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	router := app.NewRouter(categoryController)
+	if cfg.Database.AutoMigrate {
+		err = runMigration(DB, dialect, "up")
+		helper.PanicfIfErr(err)
+	}
 
-	server := http.Server{
-		Addr:    fmt.Sprintf("%s:%d", HOST, PORT),
-		Handler: middleware.NewAuthMiddleware(router),
+	var keyStore auth.KeyStore
+	if cfg.Auth.HasMethod("api_key") {
+		if strings.EqualFold(cfg.Auth.KeyStore, "memory") {
+			keyStore = auth.NewMemoryKeyStore()
+		} else {
+			keyStore = auth.NewSQLKeyStore(DB, dialect)
+		}
+		if cfg.Auth.APIKey != "" {
+			_, err = auth.EnsureKey(context.Background(), keyStore, "default", cfg.Auth.APIKey, cfg.Auth.APIKeyScopes)
+			helper.PanicfIfErr(err)
+		}
+	}
+	var verifier *auth.JWTVerifier
+	if cfg.Auth.HasMethod("jwt") {
+		verifier, err = auth.NewJWTVerifier(cfg.Auth.JWT)
+		helper.PanicfIfErr(err)
+	}
+	var signatures *auth.HMACVerifier
+	if cfg.Auth.HasMethod("hmac") {
+		signatures = auth.NewHMACVerifier(cfg.Auth.HMAC)
 	}
 
-	err := server.ListenAndServe()
+	validate := helper.NewValidator()
+	translator, err := i18n.NewTranslator(validate)
 	helper.PanicfIfErr(err)
+	categoryRepository := repository.NewCategoryRepository(dialect)
+	categoryService := service.NewCategoryService(categoryRepository, repository.NewSQLTxManager(DB), validate)
+	tracer, err := newTracer(cfg.Tracing)
+	helper.PanicfIfErr(err)
+	if tracer != nil {
+		categoryService = service.NewTracedCategoryService(categoryService)
+	}
+	categoryController := controller.NewCategoryController(categoryService, cfg.Concurrency)
+
+	var limiter *middleware.RateLimiter
+	if cfg.RateLimit.Enabled {
+		limiter = middleware.NewRateLimiter(ratelimit.NewMemoryStore(), cfg.RateLimit)
+	}
+	router := app.NewRouter(categoryController, limiter)
+
+	migrator, err := migration.NewMigrator(DB, dialect)
+	helper.PanicfIfErr(err)
+	checker := health.NewChecker(time.Duration(cfg.Server.ReadinessTimeout))
+	checker.Register("database", health.DatabaseCheck(DB))
+	checker.Register("migrations", health.MigrationsCheck(migrator))
+
+	// The probes, /metrics and the API docs bypass authentication, rate
+	// limiting and the access log.
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", checker.LivenessHandler())
+	mux.Handle("/readyz", checker.ReadinessHandler())
+	if cfg.OpenAPI.Docs {
+		mux.Handle("/openapi.json", openapi.SpecHandler())
+		mux.Handle("/docs", openapi.DocsHandler("/openapi.json", cfg.OpenAPI.DocsAssetsURL))
+	}
+
+	chain := middleware.NewChain(middleware.RequestID)
+	if tracer != nil {
+		chain = chain.Append(middleware.Tracing(tracer))
+	}
+	if cfg.Metrics.Enabled {
+		registry := metrics.NewRegistry()
+		metrics.RegisterDBStats(registry, DB)
+		mux.Handle("/metrics", metrics.Handler(registry))
+		chain = chain.Append(middleware.Metrics(metrics.NewHTTPMetrics(registry)))
+	}
+	chain = chain.Append(
+		middleware.AccessLog(log.New(os.Stdout, "", log.LstdFlags)),
+		middleware.Recovery,
+		limiter.LimitAddresses("ip"),
+		middleware.Auth(keyStore, verifier, signatures),
+		middleware.Locale(translator),
+	)
+	if cfg.OpenAPI.ValidateRequests || cfg.OpenAPI.ValidateResponses {
+		spec, err := openapi.Load(openapi.Spec)
+		helper.PanicfIfErr(err)
+		chain = chain.Append(middleware.OpenAPIValidation(spec, cfg.OpenAPI, log.Default()))
+	}
+	mux.Handle("/", chain.Then(router))
+	server := app.NewServer(cfg.Server, mux)
+
+	// Hooks stop in reverse order: /readyz starts failing for
+	// server.shutdown_delay, then the server drains in-flight requests, then
+	// the database is closed and the last spans are exported.
+	lifecycle := app.NewLifecycle()
+	if tracer != nil {
+		lifecycle.Append(app.Hook{
+			Name:   "tracing",
+			OnStop: tracer.Shutdown,
+		})
+	}
+	lifecycle.Append(app.Hook{
+		Name:    "database",
+		OnStart: DB.PingContext,
+		OnStop: func(ctx context.Context) error {
+			return DB.Close()
+		},
+	})
+	lifecycle.Append(app.ServerHook(server, lifecycle))
+	lifecycle.Append(app.ReadinessHook(checker, time.Duration(cfg.Server.ShutdownDelay)))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = lifecycle.Run(ctx, time.Duration(cfg.Server.ShutdownTimeout))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Stopped")
+}
+
+// newTracer returns nil when tracing is disabled.
+func newTracer(tracingConfig config.TracingConfig) (*sdktrace.TracerProvider, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch strings.ToLower(tracingConfig.Exporter) {
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		exporter, err = tracing.NewFileExporter(tracingConfig.File)
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(tracingConfig.OTLPEndpoint))
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tracing.NewTracerProvider(exporter, tracingConfig.ServiceName, tracingConfig.SampleRatio)
+}
 
+func runMigration(DB *sql.DB, dialect db.Dialect, command string) error {
+	ctx := context.Background()
+	migrator, err := migration.NewMigrator(DB, dialect)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		}
+		return err
+	case "down":
+		m, err := migrator.Down(ctx)
+		if err == nil {
+			log.Printf("Rolled back migration %d_%s", m.Version, m.Name)
+		}
+		return err
+	case "redo":
+		m, err := migrator.Redo(ctx)
+		if err == nil {
+			log.Printf("Redone migration %d_%s", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%06d_%-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down, redo or status", command)
+	}
+}
+
+// runAPIKey handles "apikey create [-ttl 720h] [-scopes a,b] <name>",
+// "apikey list" and "apikey revoke <id>". A created key is printed once and
+// cannot be shown again.
+func runAPIKey(keyStore auth.KeyStore, args []string) error {
+	ctx := context.Background()
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		ttl := fs.Duration("ttl", 0, "lifetime of the key, 0 for no expiry")
+		scopes := fs.String("scopes", auth.ScopeCategoriesRead, "comma-separated scopes granted to the key")
+		err := fs.Parse(args[1:])
+		if err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New("usage: apikey create [-ttl 720h] [-scopes a,b] <name>")
+		}
+
+		var expiresAt *time.Time
+		if *ttl > 0 {
+			t := time.Now().UTC().Add(*ttl)
+			expiresAt = &t
+		}
+		key, plaintext, err := auth.CreateKey(ctx, keyStore, fs.Arg(0), strings.Split(*scopes, ","), expiresAt)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created API key %d (%s): %s\n", key.Id, key.Name, plaintext)
+		return nil
+	case "list":
+		keys, err := keyStore.FindAll(ctx)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			fmt.Printf("%-6d %-30s scopes=%s expires=%s revoked=%s last_used=%s\n", key.Id, key.Name, strings.Join(key.Scopes, ","),
+				formatTime(key.ExpiresAt), formatTime(key.RevokedAt), formatTime(key.LastUsedAt))
+		}
+		return nil
+	case "revoke":
+		if len(args) < 2 {
+			return errors.New("usage: apikey revoke <id>")
+		}
+		keyId, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return err
+		}
+		err = keyStore.Revoke(ctx, keyId, time.Now().UTC())
+		if err == nil {
+			log.Printf("Revoked API key %d", keyId)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown apikey command %q, expected create, list or revoke", args[0])
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
 }
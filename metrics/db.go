@@ -0,0 +1,14 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterDBStats exposes the connection pool statistics of DB as the
+// go_sql_* metrics, read on every scrape.
+func RegisterDBStats(registerer prometheus.Registerer, DB *sql.DB) {
+	registerer.MustRegister(collectors.NewDBStatsCollector(DB, "default"))
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// UnmatchedRoute labels requests that did not reach a registered route, such
+// as 404s and requests rejected before routing.
+const UnmatchedRoute = "unmatched"
+
+// HTTPMetrics are the request metrics recorded by middleware.Metrics.
+type HTTPMetrics struct {
+	Requests *prometheus.CounterVec
+	Duration *prometheus.HistogramVec
+	Errors   *prometheus.CounterVec
+}
+
+func NewHTTPMetrics(registerer prometheus.Registerer) *HTTPMetrics {
+	httpMetrics := &HTTPMetrics{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Requests served, by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		Duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve requests, by method and route pattern.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_errors_total",
+			Help: "Error responses written by exception.ErrorHandler, by error type.",
+		}, []string{"type"}),
+	}
+	registerer.MustRegister(httpMetrics.Requests, httpMetrics.Duration, httpMetrics.Errors)
+	return httpMetrics
+}
+
+// RequestInfo collects what the handlers of a request learn about it for the
+// metrics recorded once it completes.
+type RequestInfo struct {
+	Route     string
+	ErrorType string
+}
+
+type requestInfoContextKey struct{}
+
+func NewRequestInfoContext(ctx context.Context) (context.Context, *RequestInfo) {
+	info := &RequestInfo{}
+	return context.WithValue(ctx, requestInfoContextKey{}, info), info
+}
+
+// Route records pattern, rather than the request path, as the route of the
+// requests handle serves, keeping the number of series bounded.
+func Route(pattern string, handle httprouter.Handle) httprouter.Handle {
+	return func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		if info, ok := request.Context().Value(requestInfoContextKey{}).(*RequestInfo); ok {
+			info.Route = pattern
+		}
+		handle(writer, request, params)
+	}
+}
+
+// ObserveError records the type of error a request failed with.
+func ObserveError(ctx context.Context, errorType string) {
+	if info, ok := ctx.Value(requestInfoContextKey{}).(*RequestInfo); ok {
+		info.ErrorType = errorType
+	}
+}
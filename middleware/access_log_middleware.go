@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type accessLogContextKey struct{}
+
+// accessLogEntry collects what inner middlewares learn about a request, such
+// as the credential, for the access log line written once it completes.
+type accessLogEntry struct {
+	credential string
+}
+
+// AccessLog writes one logfmt line per request with its method, path,
+// status, latency, response size, request id and the credential it was
+// authenticated with.
+func AccessLog(logger *log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &accessLogEntry{credential: "-"}
+			recorder := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), accessLogContextKey{}, entry)))
+
+			logger.Printf("method=%s path=%s status=%d latency=%s bytes=%d request_id=%s credential=%s",
+				r.Method, strconv.Quote(r.URL.Path), recorder.Status(), time.Since(start), recorder.bytes,
+				orDash(RequestIDFromContext(r.Context())), entry.credential)
+		})
+	}
+}
+
+// logCredential records the credential of an authenticated request for the
+// access log, if there is one.
+func logCredential(ctx context.Context, credential string) {
+	if entry, ok := ctx.Value(accessLogContextKey{}).(*accessLogEntry); ok {
+		entry.credential = credential
+	}
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// statusRecorder remembers the status and counts the bytes written through
+// it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	if recorder.status == 0 {
+		recorder.status = status
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(b []byte) (int, error) {
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	n, err := recorder.ResponseWriter.Write(b)
+	recorder.bytes += n
+	return n, err
+}
+
+func (recorder *statusRecorder) Status() int {
+	if recorder.status == 0 {
+		return http.StatusOK
+	}
+	return recorder.status
+}
+
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -1,34 +1,127 @@
 package middleware
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/exception"
 	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/metrics"
 	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/protocol"
 )
 
+// MaxSignedBodySize limits the body of a signed request, which is read into
+// memory to check its digest.
+const MaxSignedBodySize = 10 << 20
+
+// AuthMiddleware accepts requests carrying an "Authorization: Bearer" token
+// that Verifier accepts, an X-Signature made with a key Signatures knows, or
+// an X-API-KEY header with a key from KeyStore that is neither expired nor
+// revoked. A nil KeyStore, Verifier or Signatures disables that method. The
+// credential is stored in the request context, see auth.KeyFromContext,
+// auth.ClaimsFromContext and auth.SignerFromContext.
 type AuthMiddleware struct {
-	Handler http.Handler
+	Handler    http.Handler
+	KeyStore   auth.KeyStore
+	Verifier   *auth.JWTVerifier
+	Signatures *auth.HMACVerifier
 }
 
-func NewAuthMiddleware(handler http.Handler) *AuthMiddleware {
-	return &AuthMiddleware{Handler: handler}
+func NewAuthMiddleware(handler http.Handler, keyStore auth.KeyStore, verifier *auth.JWTVerifier, signatures *auth.HMACVerifier) *AuthMiddleware {
+	return &AuthMiddleware{
+		Handler:    handler,
+		KeyStore:   keyStore,
+		Verifier:   verifier,
+		Signatures: signatures,
+	}
 }
 
 func (middleware *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Header.Get("X-API-KEY") == "RAHASIA" {
-		middleware.Handler.ServeHTTP(w, r)
+	now := time.Now().UTC()
 
-	} else {
-		status := http.StatusUnauthorized
+	if token, ok := bearerToken(r); ok && middleware.Verifier != nil {
+		claims, err := middleware.Verifier.Verify(token, now)
+		if err != nil {
+			middleware.unauthorized(w, r)
+			return
+		}
+		middleware.serveAuthenticated(w, r.WithContext(auth.NewClaimsContext(r.Context(), claims)))
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(status)
+	if r.Header.Get(protocol.SignatureHeader) != "" && middleware.Signatures != nil {
+		middleware.serveSigned(w, r, now)
+		return
+	}
 
-		resp := webresponse.WebResponse{
-			Code:   status,
-			Status: "UNAUTHORIZED",
-		}
-		helper.WriteToResponseBody(w, resp)
+	if middleware.KeyStore == nil {
+		middleware.unauthorized(w, r)
+		return
+	}
+	key, err := auth.Authenticate(r.Context(), middleware.KeyStore, r.Header.Get("X-API-KEY"), now)
+	switch {
+	case err == nil:
+		middleware.serveAuthenticated(w, r.WithContext(auth.NewKeyContext(r.Context(), key)))
+	case errors.Is(err, auth.ErrKeyNotFound), errors.Is(err, auth.ErrKeyExpired), errors.Is(err, auth.ErrKeyRevoked):
+		middleware.unauthorized(w, r)
+	default:
+		exception.ErrorHandler(w, r, err)
+	}
+}
+
+func (middleware *AuthMiddleware) serveSigned(w http.ResponseWriter, r *http.Request, now time.Time) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxSignedBodySize+1))
+	if err != nil {
+		exception.ErrorHandler(w, r, err)
+		return
+	}
+	if len(body) > MaxSignedBodySize {
+		exception.ErrorHandler(w, r, exception.NewPayloadTooLargeError(fmt.Sprintf("signed request body is larger than %d bytes", MaxSignedBodySize)))
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	signer, err := middleware.Signatures.Verify(r, body, now)
+	if err != nil {
+		middleware.unauthorized(w, r)
+		return
+	}
+	middleware.serveAuthenticated(w, r.WithContext(auth.NewSignerContext(r.Context(), signer)))
+}
+
+func (middleware *AuthMiddleware) serveAuthenticated(w http.ResponseWriter, r *http.Request) {
+	logCredential(r.Context(), clientIdentity(r))
+	middleware.Handler.ServeHTTP(w, r)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[7:]), true
+}
+
+func (middleware *AuthMiddleware) unauthorized(w http.ResponseWriter, r *http.Request) {
+	metrics.ObserveError(r.Context(), "unauthorized")
+	status := http.StatusUnauthorized
+
+	if middleware.Verifier != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	resp := webresponse.WebResponse{
+		Code:   status,
+		Status: "UNAUTHORIZED",
 	}
+	helper.WriteToResponseBody(w, resp)
 }
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/i18n"
+)
+
+// Middleware wraps a handler in another.
+type Middleware func(http.Handler) http.Handler
+
+// Chain composes middlewares so that the first one sees a request first.
+type Chain struct {
+	middlewares []Middleware
+}
+
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain{middlewares: append([]Middleware(nil), middlewares...)}
+}
+
+// Append returns a new chain with middlewares run after those of chain.
+func (chain Chain) Append(middlewares ...Middleware) Chain {
+	combined := make([]Middleware, 0, len(chain.middlewares)+len(middlewares))
+	combined = append(combined, chain.middlewares...)
+	return Chain{middlewares: append(combined, middlewares...)}
+}
+
+func (chain Chain) Then(handler http.Handler) http.Handler {
+	for i := len(chain.middlewares) - 1; i >= 0; i-- {
+		handler = chain.middlewares[i](handler)
+	}
+	return handler
+}
+
+func Auth(keyStore auth.KeyStore, verifier *auth.JWTVerifier, signatures *auth.HMACVerifier) Middleware {
+	return func(handler http.Handler) http.Handler {
+		return NewAuthMiddleware(handler, keyStore, verifier, signatures)
+	}
+}
+
+func Locale(translator *i18n.Translator) Middleware {
+	return func(handler http.Handler) http.Handler {
+		return NewLocaleMiddleware(handler, translator)
+	}
+}
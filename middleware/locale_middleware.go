@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rtanx/golang-restful-api/i18n"
+)
+
+// LocaleMiddleware stores the translation matching the Accept-Language header
+// in the request context, where exception.ErrorHandler picks it up.
+type LocaleMiddleware struct {
+	Handler    http.Handler
+	Translator *i18n.Translator
+}
+
+func NewLocaleMiddleware(handler http.Handler, translator *i18n.Translator) *LocaleMiddleware {
+	return &LocaleMiddleware{
+		Handler:    handler,
+		Translator: translator,
+	}
+}
+
+func (middleware *LocaleMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	trans := middleware.Translator.Match(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", trans.Locale())
+	middleware.Handler.ServeHTTP(w, r.WithContext(i18n.NewContext(r.Context(), trans)))
+}
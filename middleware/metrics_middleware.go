@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/metrics"
+)
+
+// Metrics records a request count and latency per route pattern, and the
+// error type of failed requests. It should run outside Recovery so that
+// recovered panics are counted too.
+func Metrics(httpMetrics *metrics.HTTPMetrics) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, info := metrics.NewRequestInfoContext(r.Context())
+			recorder := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			route := info.Route
+			if route == "" {
+				route = metrics.UnmatchedRoute
+			}
+			method := metricsMethod(r.Method)
+			httpMetrics.Requests.WithLabelValues(method, route, strconv.Itoa(recorder.Status())).Inc()
+			httpMetrics.Duration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+			if info.ErrorType != "" {
+				httpMetrics.Errors.WithLabelValues(info.ErrorType).Inc()
+			}
+		})
+	}
+}
+
+// metricsMethod maps nonstandard methods to OTHER, so that clients cannot
+// create series at will.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/exception"
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/openapi"
+)
+
+// OpenAPIValidation checks traffic against doc. With ValidateRequests a
+// request that does not match is answered with 400 Bad Request listing the
+// offending fields, and one with a body over MaxBodyBytes with 413 Request
+// Entity Too Large; with ValidateResponses a mismatching response is sent
+// unchanged and logged to logger.
+func OpenAPIValidation(doc *openapi.Document, openAPIConfig config.OpenAPIConfig, logger *log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if openAPIConfig.ValidateRequests {
+				if r.Body != nil {
+					r.Body = http.MaxBytesReader(w, r.Body, int64(openAPIConfig.MaxBodyBytes))
+				}
+				errs, err := doc.ValidateRequest(r)
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					exception.ErrorHandler(w, r, exception.NewPayloadTooLargeError(fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit)))
+					return
+				}
+				if err != nil {
+					exception.ErrorHandler(w, r, err)
+					return
+				}
+				if len(errs) > 0 {
+					fields := make([]webresponse.ProblemFieldError, 0, len(errs))
+					for _, e := range errs {
+						fields = append(fields, webresponse.ProblemFieldError{Field: e.Field, Rule: e.Rule, Param: e.Param, Message: e.Message})
+					}
+					exception.ErrorHandler(w, r, exception.NewBadRequestFieldsError("request does not match the API specification", fields))
+					return
+				}
+			}
+			if !openAPIConfig.ValidateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			recorder := &bodyRecorder{statusRecorder: statusRecorder{ResponseWriter: w}}
+			next.ServeHTTP(recorder, r)
+
+			errs := doc.ValidateResponse(r.Method, r.URL.Path, recorder.Status(), w.Header(), recorder.body.Bytes())
+			if len(errs) > 0 {
+				messages := make([]string, 0, len(errs))
+				for _, e := range errs {
+					messages = append(messages, e.Error())
+				}
+				logger.Printf("Response to %s %s with status %d does not match the API specification: %s",
+					r.Method, r.URL.Path, recorder.Status(), strings.Join(messages, "; "))
+			}
+		})
+	}
+}
+
+// bodyRecorder keeps a copy of the body written through it.
+type bodyRecorder struct {
+	statusRecorder
+	body bytes.Buffer
+}
+
+func (recorder *bodyRecorder) Write(b []byte) (int, error) {
+	n, err := recorder.statusRecorder.Write(b)
+	recorder.body.Write(b[:n])
+	return n, err
+}
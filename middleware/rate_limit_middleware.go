@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/exception"
+	"github.com/rtanx/golang-restful-api/ratelimit"
+)
+
+// RateLimiter limits each client per route group. Clients are told apart by
+// the credential the AuthMiddleware accepted, or by IP address for
+// unauthenticated requests.
+type RateLimiter struct {
+	Store  ratelimit.Store
+	Limits map[string]ratelimit.Limit
+}
+
+func NewRateLimiter(store ratelimit.Store, rateLimitConfig config.RateLimitConfig) *RateLimiter {
+	limiter := &RateLimiter{
+		Store:  store,
+		Limits: map[string]ratelimit.Limit{},
+	}
+	for name, group := range rateLimitConfig.Groups {
+		burst := group.Burst
+		if burst == 0 {
+			burst = group.Requests
+		}
+		limiter.Limits[name] = ratelimit.Every(group.Requests, time.Duration(group.Per), burst)
+	}
+	return limiter
+}
+
+// Limit runs handle if the client has a token left in group, and otherwise
+// responds with 429 Too Many Requests. A nil RateLimiter or a group without
+// a limit leaves handle as it is. If the store fails the request is let
+// through, so that an outage of a shared store does not take the API down.
+func (limiter *RateLimiter) Limit(group string, handle httprouter.Handle) httprouter.Handle {
+	if limiter == nil {
+		return handle
+	}
+	limit, ok := limiter.Limits[group]
+	if !ok {
+		return handle
+	}
+
+	return func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		if limiter.take(writer, request, group, limit) {
+			handle(writer, request, params)
+		}
+	}
+}
+
+// LimitAddresses limits every request in group by client IP address. It
+// goes before the AuthMiddleware, where no credential is known yet, so that
+// requests with a wrong API key, token or signature are limited too.
+func (limiter *RateLimiter) LimitAddresses(group string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		limit, ok := limiter.Limits[group]
+		if !ok {
+			return next
+		}
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if limiter.take(writer, request, group, limit) {
+				next.ServeHTTP(writer, request)
+			}
+		})
+	}
+}
+
+// take reports whether the request may go on, having written the 429
+// response if not.
+func (limiter *RateLimiter) take(writer http.ResponseWriter, request *http.Request, group string, limit ratelimit.Limit) bool {
+	result, err := limiter.Store.Take(request.Context(), group+":"+clientIdentity(request), limit, time.Now())
+	if err != nil {
+		log.Printf("rate limit store: %v", err)
+		return true
+	}
+
+	writer.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	writer.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	writer.Header().Set("RateLimit-Reset", seconds(result.ResetAfter))
+	if !result.Allowed {
+		writer.Header().Set("Retry-After", seconds(result.RetryAfter))
+		exception.ErrorHandler(writer, request, exception.NewTooManyRequestsError("rate limit exceeded, retry later"))
+		return false
+	}
+	return true
+}
+
+func clientIdentity(request *http.Request) string {
+	if key, ok := auth.KeyFromContext(request.Context()); ok {
+		return "key:" + strconv.FormatInt(key.Id, 10)
+	}
+	if claims, ok := auth.ClaimsFromContext(request.Context()); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	if signer, ok := auth.SignerFromContext(request.Context()); ok {
+		return "hmac:" + signer.Id
+	}
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// seconds rounds d up to whole seconds, as the rate limit headers expect.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rtanx/golang-restful-api/exception"
+)
+
+// Recovery turns a panic anywhere below it, including in other middlewares,
+// into the response exception.ErrorHandler writes for it. The
+// http.ErrAbortHandler sentinel is re-raised so that net/http can abort the
+// response.
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				exception.ErrorHandler(w, r, err)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
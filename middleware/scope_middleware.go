@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/exception"
+)
+
+// RequireScope runs handle only if the credential the AuthMiddleware accepted
+// grants scope, and otherwise responds with 403 Forbidden.
+func RequireScope(scope string, handle httprouter.Handle) httprouter.Handle {
+	return func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		if !auth.HasScope(auth.ScopesFromContext(request.Context()), scope) {
+			exception.ErrorHandler(writer, request, exception.NewForbiddenError("missing scope "+scope))
+			return
+		}
+		handle(writer, request, params)
+	}
+}
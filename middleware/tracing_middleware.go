@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rtanx/golang-restful-api/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for each request, continuing the trace in the
+// caller's traceparent header if there is one. app.NewRouter renames the
+// span after the route pattern once the request is routed.
+func Tracing(provider trace.TracerProvider) Middleware {
+	tracer := provider.Tracer(tracing.ScopeName)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer))
+			span.SetAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path))
+			if r.URL.RawQuery != "" {
+				span.SetAttributes(semconv.URLQuery(r.URL.RawQuery))
+			}
+			if id := RequestIDFromContext(ctx); id != "" {
+				span.SetAttributes(attribute.String("http.request_id", id))
+			}
+			recorder := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.Status()))
+			if recorder.Status() >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(recorder.Status()))
+			}
+			span.End()
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// APIKey is a credential accepted in the X-API-KEY header. Only the SHA-256
+// hash of the key is stored.
+type APIKey struct {
+	Id         int64
+	Name       string
+	Hash       string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+}
@@ -1,6 +1,10 @@
 package domain
 
+// Category.Version starts at 1 and is incremented by every update, so that
+// an update can be made conditional on the version the caller last read.
 type Category struct {
-	Id   int64
-	Name string
+	Id       int64
+	Name     string
+	ParentId *int64
+	Version  int64
 }
@@ -1,5 +1,6 @@
 package webrequest
 
 type CategoryCreateRequest struct {
-	Name string `validate:"required,max=200,min=1" json:"name"`
+	Name     string `validate:"required,max=200,min=1" json:"name"`
+	ParentId *int64 `validate:"omitempty,min=1" json:"parent_id"`
 }
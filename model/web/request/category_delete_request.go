@@ -0,0 +1,8 @@
+package webrequest
+
+// CategoryDeleteRequest only deletes the category while its version is one
+// of IfMatch; an empty IfMatch deletes any version.
+type CategoryDeleteRequest struct {
+	Id      int64   `validate:"required" json:"-"`
+	IfMatch []int64 `json:"-"`
+}
@@ -0,0 +1,12 @@
+package webrequest
+
+type CategoryListRequest struct {
+	Page      int    `validate:"omitempty,min=1" json:"page" doc:"Page number, starting at 1"`
+	Size      int    `validate:"omitempty,min=1,max=100" json:"size" doc:"Page size"`
+	Limit     int    `validate:"omitempty,min=1,max=100" json:"limit" doc:"Alias of size used with offset"`
+	Offset    int    `validate:"omitempty,min=0" json:"offset" doc:"Number of categories to skip"`
+	Sort      string `validate:"omitempty,max=100" json:"sort" doc:"Comma-separated fields, - for descending, e.g. -name,id"`
+	Name      string `validate:"omitempty,max=200" json:"name" doc:"Filter by name"`
+	NameMatch string `validate:"omitempty,oneof=prefix contains" json:"name_match" doc:"How name is matched"`
+	Cursor    string `validate:"omitempty,max=1000" json:"cursor" doc:"next_cursor of the previous page"`
+}
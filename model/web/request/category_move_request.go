@@ -0,0 +1,10 @@
+package webrequest
+
+// CategoryMoveRequest re-parents a category; a nil ParentId makes it a root.
+// Like CategoryUpdateRequest, the move only applies while the version of the
+// category is one of IfMatch; an empty IfMatch moves any version.
+type CategoryMoveRequest struct {
+	Id       int64   `validate:"required" json:"-"`
+	ParentId *int64  `validate:"omitempty,min=1" json:"parent_id"`
+	IfMatch  []int64 `json:"-"`
+}
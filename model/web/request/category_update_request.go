@@ -1,6 +1,12 @@
 package webrequest
 
+// CategoryUpdateRequest leaves the parent unchanged when ParentId is nil; use
+// CategoryMoveRequest to turn a category back into a root. The update only
+// applies while the version of the category is one of IfMatch; an empty
+// IfMatch updates any version.
 type CategoryUpdateRequest struct {
-	Id   int64  `validate:"required" json:"id"`
-	Name string `validate:"required,max=200,min=1" json:"name"`
+	Id       int64   `validate:"required" json:"-"`
+	Name     string  `validate:"required,max=200,min=1" json:"name"`
+	ParentId *int64  `validate:"omitempty,min=1" json:"parent_id"`
+	IfMatch  []int64 `json:"-"`
 }
@@ -0,0 +1,6 @@
+package webresponse
+
+type CategoryListResponse struct {
+	Categories []CategoryResponse
+	Meta       interface{}
+}
@@ -1,6 +1,8 @@
 package webresponse
 
 type CategoryResponse struct {
-	Id   int64  `json:"id"`
-	Name string `json:"name"`
+	Id       int64  `json:"id"`
+	Name     string `json:"name"`
+	ParentId *int64 `json:"parent_id"`
+	Version  int64  `json:"version"`
 }
@@ -0,0 +1,8 @@
+package webresponse
+
+type CategoryTreeResponse struct {
+	Id       int64                  `json:"id"`
+	Name     string                 `json:"name"`
+	ParentId *int64                 `json:"parent_id"`
+	Children []CategoryTreeResponse `json:"children"`
+}
@@ -0,0 +1,16 @@
+package webresponse
+
+type PageMeta struct {
+	Page       int    `json:"page"`
+	Size       int    `json:"size"`
+	Offset     int    `json:"offset"`
+	TotalItems int64  `json:"total_items"`
+	TotalPages int64  `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type CursorMeta struct {
+	Size       int    `json:"size"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
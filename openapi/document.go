@@ -0,0 +1,227 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Document is the subset of an OpenAPI 3.0 document that this API uses.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	basePath string
+	routes   []route
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty"`
+	Patch  *Operation `json:"patch,omitempty"`
+}
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	AnyOf       []*Schema          `json:"anyOf,omitempty"`
+}
+
+type Components struct {
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+	Parameters      map[string]*Parameter      `json:"parameters,omitempty"`
+	Responses       map[string]*Response       `json:"responses,omitempty"`
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type route struct {
+	method    string
+	segments  []string
+	params    int
+	operation *Operation
+}
+
+// Load parses an OpenAPI document and prepares it for matching requests.
+// Paths are matched below the path of the first server URL.
+func Load(data []byte) (*Document, error) {
+	doc := &Document{}
+	err := json.Unmarshal(data, doc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI document: %w", err)
+	}
+
+	if len(doc.Servers) > 0 {
+		serverURL, err := url.Parse(doc.Servers[0].URL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing server URL: %w", err)
+		}
+		doc.basePath = strings.TrimSuffix(serverURL.Path, "/")
+	}
+
+	for path, item := range doc.Paths {
+		segments := strings.Split(strings.Trim(path, "/"), "/")
+		params := 0
+		for _, segment := range segments {
+			if isTemplate(segment) {
+				params++
+			}
+		}
+		for method, operation := range item.operations() {
+			doc.routes = append(doc.routes, route{method: method, segments: segments, params: params, operation: operation})
+		}
+	}
+	return doc, nil
+}
+
+func (item *PathItem) operations() map[string]*Operation {
+	operations := map[string]*Operation{}
+	for method, operation := range map[string]*Operation{
+		"GET": item.Get, "PUT": item.Put, "POST": item.Post, "DELETE": item.Delete, "PATCH": item.Patch,
+	} {
+		if operation != nil {
+			operations[method] = operation
+		}
+	}
+	return operations
+}
+
+// FindOperation returns the operation that documents method and path, with
+// the values of its path parameters. Literal segments win over parameters.
+func (doc *Document) FindOperation(method string, path string) (*Operation, map[string]string, bool) {
+	if !strings.HasPrefix(path, doc.basePath+"/") {
+		return nil, nil, false
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, doc.basePath), "/"), "/")
+
+	var best *route
+	for i := range doc.routes {
+		r := &doc.routes[i]
+		if r.method != method || len(r.segments) != len(segments) || (best != nil && r.params >= best.params) {
+			continue
+		}
+		if r.matches(segments) {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil, nil, false
+	}
+
+	params := map[string]string{}
+	for i, segment := range best.segments {
+		if isTemplate(segment) {
+			params[segment[1:len(segment)-1]] = segments[i]
+		}
+	}
+	return best.operation, params, true
+}
+
+func (r *route) matches(segments []string) bool {
+	for i, segment := range r.segments {
+		if !isTemplate(segment) && segment != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isTemplate(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func (doc *Document) schema(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+func (doc *Document) parameter(parameter *Parameter) *Parameter {
+	for parameter != nil && parameter.Ref != "" {
+		parameter = doc.Components.Parameters[strings.TrimPrefix(parameter.Ref, "#/components/parameters/")]
+	}
+	return parameter
+}
+
+func (doc *Document) response(response *Response) *Response {
+	for response != nil && response.Ref != "" {
+		response = doc.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+	}
+	return response
+}
@@ -0,0 +1,214 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Generator derives schemas from Go types. Fields are named by their json
+// tag and constrained by their validate tag: required, min and max (a
+// length for strings, a bound for numbers) and oneof become required,
+// minLength/maxLength or minimum/maximum and enum. Named structs become
+// components referenced by their type name.
+type Generator struct {
+	Document *Document
+}
+
+func NewGenerator(info Info, serverURL string) *Generator {
+	return &Generator{Document: &Document{
+		OpenAPI: "3.0.2",
+		Info:    info,
+		Servers: []Server{{URL: serverURL}},
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			SecuritySchemes: map[string]*SecurityScheme{},
+			Responses:       map[string]*Response{},
+			Schemas:         map[string]*Schema{},
+		},
+	}}
+}
+
+// RequestSchema describes value as a request body, whose required fields are
+// those validated as required.
+func (g *Generator) RequestSchema(value interface{}) *Schema {
+	return g.schema(reflect.TypeOf(value), true)
+}
+
+// ResponseSchema describes value as a response body, whose required fields
+// are those not tagged omitempty.
+func (g *Generator) ResponseSchema(value interface{}) *Schema {
+	return g.schema(reflect.TypeOf(value), false)
+}
+
+// InlineResponseSchema is ResponseSchema for a struct that is not made a
+// component, so that the caller can adapt its properties.
+func (g *Generator) InlineResponseSchema(value interface{}) *Schema {
+	return g.structSchema(reflect.TypeOf(value), false)
+}
+
+// QueryParameters describes the fields of a struct as query parameters.
+// A doc tag on a field becomes its description.
+func (g *Generator) QueryParameters(value interface{}) []*Parameter {
+	t := reflect.TypeOf(value)
+	var parameters []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		schema := g.schema(field.Type, true)
+		required := applyValidateTag(schema, field.Tag.Get("validate"))
+		parameters = append(parameters, &Parameter{
+			Name:        name,
+			In:          "query",
+			Required:    required,
+			Description: field.Tag.Get("doc"),
+			Schema:      schema,
+		})
+	}
+	return parameters
+}
+
+func (g *Generator) AddOperation(method string, path string, operation *Operation) {
+	item, ok := g.Document.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		g.Document.Paths[path] = item
+	}
+	switch method {
+	case "GET":
+		item.Get = operation
+	case "PUT":
+		item.Put = operation
+	case "POST":
+		item.Post = operation
+	case "DELETE":
+		item.Delete = operation
+	case "PATCH":
+		item.Patch = operation
+	}
+}
+
+// Marshal formats a document the way apispec.json is committed.
+func Marshal(doc *Document) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	err := encoder.Encode(doc)
+	return buf.Bytes(), err
+}
+
+func (g *Generator) schema(t reflect.Type, request bool) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := g.schema(t.Elem(), request)
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t, request)
+		}
+		if _, ok := g.Document.Components.Schemas[t.Name()]; !ok {
+			// The placeholder ends recursion through self-referencing types.
+			g.Document.Components.Schemas[t.Name()] = &Schema{}
+			*g.Document.Components.Schemas[t.Name()] = *g.structSchema(t, request)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Nullable: t.Kind() == reflect.Slice, Items: g.schema(t.Elem(), request)}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	default:
+		return &Schema{Nullable: true}
+	}
+}
+
+func (g *Generator) structSchema(t reflect.Type, request bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+		property := g.schema(field.Type, request)
+		validateRequired := applyValidateTag(property, field.Tag.Get("validate"))
+		if (request && validateRequired) || (!request && !omitempty) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// applyValidateTag reports whether the tag makes the field required.
+func applyValidateTag(schema *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			key, param = rule[:i], rule[i+1:]
+		}
+		switch key {
+		case "required":
+			required = true
+		case "min", "max":
+			bound, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			switch {
+			case schema.Type == "string" && key == "min":
+				length := int(bound)
+				schema.MinLength = &length
+			case schema.Type == "string":
+				length := int(bound)
+				schema.MaxLength = &length
+			case (schema.Type == "integer" || schema.Type == "number") && key == "min":
+				schema.Minimum = &bound
+			case schema.Type == "integer" || schema.Type == "number":
+				schema.Maximum = &bound
+			}
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		}
+	}
+	return required
+}
+
+func jsonName(field reflect.StructField) (name string, omitempty bool, ok bool) {
+	if field.PkgPath != "" {
+		return "", false, false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, true
+}
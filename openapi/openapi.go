@@ -0,0 +1,72 @@
+package openapi
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:generate go run ../cmd/openapi-gen -o apispec.json
+
+// Spec is the OpenAPI document of the API, served at /openapi.json. It is
+// generated from app.Routes; CI checks it with go run ./cmd/openapi-gen
+// -check -o openapi/apispec.json.
+//
+//go:embed apispec.json
+var Spec []byte
+
+func SpecHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(Spec)
+	})
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Category RESTful API</title>
+<link rel="stylesheet" href="{{.AssetsURL}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.AssetsURL}}/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = function () {
+  SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>
+`))
+
+// DocsHandler serves a Swagger UI page for the document at specURL. The UI
+// itself is loaded from assetsURL, which serves the files of the
+// swagger-ui-dist package, e.g. https://unpkg.com/swagger-ui-dist@5.
+func DocsHandler(specURL string, assetsURL string) http.Handler {
+	data := struct {
+		SpecURL   string
+		AssetsURL string
+	}{SpecURL: specURL, AssetsURL: strings.TrimSuffix(assetsURL, "/")}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		docsTemplate.Execute(w, data)
+	})
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStrings(values []string) []string {
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,284 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError is a value that does not match its schema. Rule is the
+// schema keyword it breaks and Param the keyword's value, if any.
+type ValidationError struct {
+	Field   string
+	Rule    string
+	Param   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidateRequest checks the parameters and body of r against the operation
+// that documents it. The body is read and restored for the next handler.
+// Requests that no operation documents are not checked.
+func (doc *Document) ValidateRequest(r *http.Request) ([]ValidationError, error) {
+	operation, pathParams, ok := doc.FindOperation(r.Method, r.URL.Path)
+	if !ok {
+		return nil, nil
+	}
+
+	var errs []ValidationError
+	query := r.URL.Query()
+	for _, parameter := range operation.Parameters {
+		parameter = doc.parameter(parameter)
+		if parameter == nil {
+			continue
+		}
+
+		var raw string
+		var present bool
+		switch parameter.In {
+		case "path":
+			raw, present = pathParams[parameter.Name]
+		case "query":
+			_, present = query[parameter.Name]
+			raw = query.Get(parameter.Name)
+		case "header":
+			raw = r.Header.Get(parameter.Name)
+			present = raw != ""
+		}
+		if !present {
+			if parameter.Required {
+				errs = append(errs, ValidationError{Field: parameter.Name, Rule: "required", Message: "is required"})
+			}
+			continue
+		}
+		doc.validate(parameterValue(raw, doc.schema(parameter.Schema)), parameter.Schema, parameter.Name, &errs)
+	}
+
+	if operation.RequestBody == nil {
+		return errs, nil
+	}
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if operation.RequestBody.Required {
+			errs = append(errs, ValidationError{Field: "body", Rule: "required", Message: "is required"})
+		}
+		return errs, nil
+	}
+	errs = append(errs, doc.validateContent(operation.RequestBody.Content, r.Header.Get("Content-Type"), body)...)
+	return errs, nil
+}
+
+// ValidateResponse checks a response to method and path against the
+// operation that documents it.
+func (doc *Document) ValidateResponse(method string, path string, status int, header http.Header, body []byte) []ValidationError {
+	operation, _, ok := doc.FindOperation(method, path)
+	if !ok {
+		return nil
+	}
+
+	code := strconv.Itoa(status)
+	response, ok := operation.Responses[code]
+	if !ok {
+		response, ok = operation.Responses[code[:1]+"XX"]
+	}
+	if !ok {
+		response = operation.Responses["default"]
+	}
+	response = doc.response(response)
+	if response == nil {
+		return []ValidationError{{Field: "status", Rule: "responses", Param: code, Message: "is not documented"}}
+	}
+	if len(response.Content) == 0 {
+		return nil
+	}
+	return doc.validateContent(response.Content, header.Get("Content-Type"), body)
+}
+
+func (doc *Document) validateContent(content map[string]*MediaType, contentType string, body []byte) []ValidationError {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := content[mediaType]
+	if !ok {
+		var types []string
+		for t := range content {
+			types = append(types, t)
+		}
+		return []ValidationError{{Field: "Content-Type", Rule: "content", Message: "must be one of " + strings.Join(sortedStrings(types), ", ")}}
+	}
+	if media == nil || media.Schema == nil {
+		return nil
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(&value)
+	if err != nil {
+		return []ValidationError{{Field: "body", Rule: "json", Message: "must be valid JSON"}}
+	}
+
+	var errs []ValidationError
+	doc.validate(value, media.Schema, "", &errs)
+	return errs
+}
+
+// parameterValue converts a parameter to the type of its schema, leaving it
+// a string if it does not parse so that validate reports the mismatch.
+func parameterValue(raw string, schema *Schema) interface{} {
+	if schema == nil {
+		return raw
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case "boolean":
+		if parsed, err := strconv.ParseBool(raw); err == nil {
+			return parsed
+		}
+	}
+	return raw
+}
+
+func (doc *Document) validate(value interface{}, schema *Schema, field string, errs *[]ValidationError) {
+	schema = doc.schema(schema)
+	if schema == nil {
+		return
+	}
+	fail := func(rule string, param string, format string, args ...interface{}) {
+		name := field
+		if name == "" {
+			name = "body"
+		}
+		*errs = append(*errs, ValidationError{Field: name, Rule: rule, Param: param, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if value == nil {
+		if !schema.Nullable {
+			fail("nullable", "", "must not be null")
+		}
+		return
+	}
+
+	if len(schema.AnyOf) > 0 {
+		for _, option := range schema.AnyOf {
+			var optionErrs []ValidationError
+			doc.validate(value, option, field, &optionErrs)
+			if len(optionErrs) == 0 {
+				return
+			}
+		}
+		fail("anyOf", "", "does not match any of the allowed schemas")
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("type", "object", "must be an object")
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				*errs = append(*errs, ValidationError{Field: joinField(field, name), Rule: "required", Message: "is required"})
+			}
+		}
+		for _, name := range sortedKeys(schema.Properties) {
+			if propertyValue, ok := object[name]; ok {
+				doc.validate(propertyValue, schema.Properties[name], joinField(field, name), errs)
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			fail("type", "array", "must be an array")
+			return
+		}
+		for i, item := range array {
+			doc.validate(item, schema.Items, fmt.Sprintf("%s[%d]", field, i), errs)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("type", "string", "must be a string")
+			return
+		}
+		length := utf8.RuneCountInString(s)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("minLength", strconv.Itoa(*schema.MinLength), "must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("maxLength", strconv.Itoa(*schema.MaxLength), "must be at most %d characters long", *schema.MaxLength)
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, s) {
+			fail("enum", strings.Join(schema.Enum, " "), "must be one of %s", strings.Join(schema.Enum, ", "))
+		}
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			fail("type", schema.Type, "must be %s", article(schema.Type))
+			return
+		}
+		parsed, err := number.Float64()
+		if err != nil || (schema.Type == "integer" && strings.ContainsAny(number.String(), ".eE")) {
+			fail("type", schema.Type, "must be %s", article(schema.Type))
+			return
+		}
+		if schema.Minimum != nil && parsed < *schema.Minimum {
+			fail("minimum", formatNumber(*schema.Minimum), "must be at least %s", formatNumber(*schema.Minimum))
+		}
+		if schema.Maximum != nil && parsed > *schema.Maximum {
+			fail("maximum", formatNumber(*schema.Maximum), "must be at most %s", formatNumber(*schema.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("type", "boolean", "must be a boolean")
+		}
+	}
+}
+
+func joinField(field string, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func article(typeName string) string {
+	if typeName == "integer" {
+		return "an integer"
+	}
+	return "a " + typeName
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
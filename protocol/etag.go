@@ -0,0 +1,40 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag formats the version of a resource as a strong entity tag.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseETags parses the entity tags of an If-Match or If-None-Match header
+// into versions; any reports "*". If-Match compares strongly, so weak tags
+// are only accepted when weak is set. Tags that are not versions parse as
+// 0, which no resource has.
+func ParseETags(header string, weak bool) (versions []int64, any bool) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if tag == "*" {
+			return nil, true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				versions = append(versions, 0)
+				continue
+			}
+			tag = tag[2:]
+		}
+		version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+		if err != nil || len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			version = 0
+		}
+		versions = append(versions, version)
+	}
+	return versions, false
+}
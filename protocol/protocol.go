@@ -0,0 +1,8 @@
+// Package protocol holds the conventions of the API on the wire that the
+// server and the client package share: content types, entity tags and
+// request signatures. It depends on the standard library only, so that the
+// client does not pull in the server's dependencies.
+package protocol
+
+// ProblemContentType is the media type of RFC 7807 problem responses.
+const ProblemContentType = "application/problem+json"
@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureKeyIdHeader     = "X-Signature-Key-Id"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+)
+
+// CanonicalRequest is the string a request signature covers: the method, the
+// escaped path, the query with its parameters sorted, the hex SHA-256 of the
+// body, the Unix timestamp and the nonce, separated by newlines.
+func CanonicalRequest(r *http.Request, body []byte, timestamp string, nonce string) string {
+	digest := sha256.Sum256(body)
+	return strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		hex.EncodeToString(digest[:]),
+		timestamp,
+		nonce,
+	}, "\n")
+}
+
+func Sign(secret string, canonical string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(canonical))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets the signature headers on r for body, which must be the
+// exact bytes sent as the request body.
+func SignRequest(r *http.Request, body []byte, keyId string, secret string, now time.Time, nonce string) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	r.Header.Set(SignatureKeyIdHeader, keyId)
+	r.Header.Set(SignatureTimestampHeader, timestamp)
+	r.Header.Set(SignatureNonceHeader, nonce)
+	r.Header.Set(SignatureHeader, Sign(secret, CanonicalRequest(r, body, timestamp, nonce)))
+}
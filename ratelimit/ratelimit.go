@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: it holds up to Burst tokens and refills at Rate
+// tokens per second. Each request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every returns the limit allowing requests per interval, in bursts of up to
+// burst requests.
+func Every(requests int, interval time.Duration, burst int) Limit {
+	return Limit{Rate: float64(requests) / interval.Seconds(), Burst: burst}
+}
+
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token is available.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store keeps the buckets. Implementations backed by a shared store let
+// several instances enforce one limit.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// MemoryStore keeps buckets in a map. Buckets that have refilled completely
+// are pruned, at most once a minute.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	limits  map[string]Limit
+	pruned  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		limits:  map[string]Limit{},
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if now.Sub(store.pruned) >= time.Minute {
+		for k, b := range store.buckets {
+			if refill(b, store.limits[k], now) >= float64(store.limits[k].Burst) {
+				delete(store.buckets, k)
+				delete(store.limits, k)
+			}
+		}
+		store.pruned = now
+	}
+
+	b, ok := store.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		store.buckets[key] = b
+	}
+	store.limits[key] = limit
+
+	b.tokens = refill(b, limit, now)
+	b.updated = now
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = durationFor(1-b.tokens, limit)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.ResetAfter = durationFor(float64(limit.Burst)-b.tokens, limit)
+	return result, nil
+}
+
+func refill(b *bucket, limit Limit, now time.Time) float64 {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+}
+
+func durationFor(tokens float64, limit Limit) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if limit.Rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / limit.Rate * float64(time.Second))
+}
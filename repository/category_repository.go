@@ -2,15 +2,88 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 
 	"github.com/rtanx/golang-restful-api/model/domain"
 )
 
+var (
+	ErrCategoryNotFound      = errors.New("category is not found")
+	ErrDuplicateCategoryName = errors.New("category name already exists")
+	ErrCategoryVersion       = errors.New("category has been modified since it was read")
+)
+
+const (
+	NameMatchContains = "contains"
+	NameMatchPrefix   = "prefix"
+)
+
+// CategoryQuery narrows and orders the categories returned by FindAll.
+// Sort fields are limited to "id" and "name"; a zero Limit means no limit.
+// When After is set only the categories that come after it in the sort order
+// are returned, which gives keyset pagination on the sort key. ParentId
+// restricts the result to the direct children of that category.
+type CategoryQuery struct {
+	ParentId  *int64
+	Name      string
+	NameMatch string
+	Sort      []SortField
+	After     *domain.Category
+	Limit     int
+	Offset    int
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+var CategorySortFields = []string{"id", "name"}
+
+// Save and Update return ErrDuplicateCategoryName when another category
+// already has the same name, ignoring case. Save stores version 1. Update
+// and Delete only apply while the stored version is still category.Version,
+// returning ErrCategoryVersion otherwise; Update increments the version.
 type CategoryRepository interface {
-	Save(ctx context.Context, tx *sql.Tx, category domain.Category) domain.Category
-	Update(ctx context.Context, tx *sql.Tx, category domain.Category) domain.Category
-	Delete(ctx context.Context, tx *sql.Tx, category domain.Category)
-	FindById(ctx context.Context, tx *sql.Tx, categoryId int64) (domain.Category, error)
-	FindAll(ctx context.Context, tx *sql.Tx) []domain.Category
+	Save(ctx context.Context, tx Tx, category domain.Category) (domain.Category, error)
+	Update(ctx context.Context, tx Tx, category domain.Category) (domain.Category, error)
+	Delete(ctx context.Context, tx Tx, category domain.Category) error
+	FindById(ctx context.Context, tx Tx, categoryId int64) (domain.Category, error)
+	// FindByName matches names case-insensitively.
+	FindByName(ctx context.Context, tx Tx, name string) (domain.Category, error)
+	FindAll(ctx context.Context, tx Tx, query CategoryQuery) ([]domain.Category, error)
+	Count(ctx context.Context, tx Tx, query CategoryQuery) (int64, error)
+	// FindAncestors returns the ancestors of the category, root first,
+	// excluding the category itself.
+	FindAncestors(ctx context.Context, tx Tx, categoryId int64) ([]domain.Category, error)
+	// FindSubtree returns the category followed by all of its descendants,
+	// ordered by depth.
+	FindSubtree(ctx context.Context, tx Tx, categoryId int64) ([]domain.Category, error)
+}
+
+// EffectiveSort returns the sort fields FindAll orders by: the whitelisted
+// fields of sort followed by id, so that the order is total.
+func EffectiveSort(sort []SortField) []SortField {
+	var fields []SortField
+	hasId := false
+	for _, field := range sort {
+		if !isCategorySortField(field.Field) {
+			continue
+		}
+		fields = append(fields, field)
+		hasId = hasId || field.Field == "id"
+	}
+	if !hasId {
+		fields = append(fields, SortField{Field: "id"})
+	}
+	return fields
+}
+
+func isCategorySortField(field string) bool {
+	for _, allowed := range CategorySortFields {
+		if field == allowed {
+			return true
+		}
+	}
+	return false
 }
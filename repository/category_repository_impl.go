@@ -3,75 +3,295 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"strings"
 
-	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/db"
 	"github.com/rtanx/golang-restful-api/model/domain"
 )
 
 type CategoryRepositoryImpl struct {
+	Dialect db.Dialect
 }
 
-func NewCategoryRepository() CategoryRepository {
-	return &CategoryRepositoryImpl{}
+func NewCategoryRepository(dialect db.Dialect) CategoryRepository {
+	return &CategoryRepositoryImpl{
+		Dialect: dialect,
+	}
 }
 
-func (respository *CategoryRepositoryImpl) Save(ctx context.Context, tx *sql.Tx, category domain.Category) domain.Category {
-	SQL := "INSERT INTO category(name) VALUES (?)"
-	res, err := tx.ExecContext(ctx, SQL, category.Name)
-	helper.PanicfIfErr(err)
-
-	id, err := res.LastInsertId()
+func (respository *CategoryRepositoryImpl) Save(ctx context.Context, tx Tx, category domain.Category) (domain.Category, error) {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return category, err
+	}
 
-	helper.PanicfIfErr(err)
+	SQL := "INSERT INTO category(name, parent_id, version) VALUES (?, ?, 1)"
+	id, err := respository.Dialect.InsertReturningId(ctx, sqlTx, SQL, category.Name, category.ParentId)
+	if respository.Dialect.IsUniqueViolation(err) {
+		return category, ErrDuplicateCategoryName
+	}
+	if err != nil {
+		return category, err
+	}
 
 	category.Id = id
-	return category
+	category.Version = 1
+	return category, nil
+}
+
+func (respository *CategoryRepositoryImpl) Update(ctx context.Context, tx Tx, category domain.Category) (domain.Category, error) {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return category, err
+	}
 
+	SQL := "UPDATE category SET name = ?, parent_id = ?, version = version + 1 WHERE id = ? AND version = ?"
+	result, err := sqlTx.ExecContext(ctx, respository.Dialect.Rebind(SQL), category.Name, category.ParentId, category.Id, category.Version)
+	if respository.Dialect.IsUniqueViolation(err) {
+		return category, ErrDuplicateCategoryName
+	}
+	if err != nil {
+		return category, err
+	}
+	err = checkVersionApplied(result)
+	if err != nil {
+		return category, err
+	}
+	category.Version++
+	return category, nil
 }
 
-func (respository *CategoryRepositoryImpl) Update(ctx context.Context, tx *sql.Tx, category domain.Category) domain.Category {
-	SQL := "UPDATE category SET name = ? WHERE id = ?"
-	_, err := tx.ExecContext(ctx, SQL, category.Name, category.Id)
-	helper.PanicfIfErr(err)
-	return category
+func (respository *CategoryRepositoryImpl) Delete(ctx context.Context, tx Tx, category domain.Category) error {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return err
+	}
+
+	SQL := "DELETE FROM category WHERE id = ? AND version = ?"
+	result, err := sqlTx.ExecContext(ctx, respository.Dialect.Rebind(SQL), category.Id, category.Version)
+	if err != nil {
+		return err
+	}
+	return checkVersionApplied(result)
 }
 
-func (respository *CategoryRepositoryImpl) Delete(ctx context.Context, tx *sql.Tx, category domain.Category) {
-	SQL := "DELETE FROM category WHERE id = ?"
-	_, err := tx.ExecContext(ctx, SQL, category.Id)
-	helper.PanicfIfErr(err)
+// checkVersionApplied reports ErrCategoryVersion when the statement matched
+// no row. The service reads the category in the same transaction first, so
+// a missing row means another transaction changed the version since.
+func checkVersionApplied(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCategoryVersion
+	}
+	return nil
 }
 
-func (respository *CategoryRepositoryImpl) FindById(ctx context.Context, tx *sql.Tx, categoryId int64) (domain.Category, error) {
-	SQL := "SELECT id, name FROM category WHERE id = ?"
-	resRows, err := tx.QueryContext(ctx, SQL, categoryId)
+func (respository *CategoryRepositoryImpl) FindById(ctx context.Context, tx Tx, categoryId int64) (domain.Category, error) {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return domain.Category{}, err
+	}
 
-	helper.PanicfIfErr(err)
+	SQL := "SELECT id, name, parent_id, version FROM category WHERE id = ?"
+	resRows, err := sqlTx.QueryContext(ctx, respository.Dialect.Rebind(SQL), categoryId)
+	if err != nil {
+		return domain.Category{}, err
+	}
 	defer resRows.Close()
 
-	category := domain.Category{}
 	if resRows.Next() {
-		err = resRows.Scan(&category.Id, &category.Name)
-		helper.PanicfIfErr(err)
-		return category, nil
+		return scanCategory(resRows)
+	} else if err = resRows.Err(); err != nil {
+		return domain.Category{}, err
 	} else {
-		return category, errors.New("category is not found")
+		return domain.Category{}, ErrCategoryNotFound
+	}
+}
+
+func (respository *CategoryRepositoryImpl) FindByName(ctx context.Context, tx Tx, name string) (domain.Category, error) {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return domain.Category{}, err
+	}
+
+	SQL := "SELECT id, name, parent_id, version FROM category WHERE LOWER(name) = LOWER(?)"
+	categories, err := respository.queryCategories(ctx, sqlTx, SQL, name)
+	if err != nil {
+		return domain.Category{}, err
+	}
+	if len(categories) == 0 {
+		return domain.Category{}, ErrCategoryNotFound
+	}
+	return categories[0], nil
+}
+
+func (respository *CategoryRepositoryImpl) FindAll(ctx context.Context, tx Tx, query CategoryQuery) ([]domain.Category, error) {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := categoryWhere(query)
+	SQL := "SELECT id, name, parent_id, version FROM category" + where + categoryOrderBy(query.Sort)
+	if query.Limit > 0 {
+		SQL += " LIMIT ? OFFSET ?"
+		args = append(args, query.Limit, query.Offset)
+	}
+	return respository.queryCategories(ctx, sqlTx, SQL, args...)
+}
+
+func (respository *CategoryRepositoryImpl) Count(ctx context.Context, tx Tx, query CategoryQuery) (int64, error) {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	query.After = nil
+	where, args := categoryWhere(query)
+	SQL := "SELECT COUNT(*) FROM category" + where
+
+	var count int64
+	err = sqlTx.QueryRowContext(ctx, respository.Dialect.Rebind(SQL), args...).Scan(&count)
+	return count, err
+}
+
+func (respository *CategoryRepositoryImpl) FindAncestors(ctx context.Context, tx Tx, categoryId int64) ([]domain.Category, error) {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	SQL := `WITH RECURSIVE ancestors(id, name, parent_id, version, depth) AS (
+		SELECT id, name, parent_id, version, 0 FROM category WHERE id = ?
+		UNION ALL
+		SELECT c.id, c.name, c.parent_id, c.version, a.depth + 1 FROM category c JOIN ancestors a ON c.id = a.parent_id
+	)
+	SELECT id, name, parent_id, version FROM ancestors WHERE depth > 0 ORDER BY depth DESC`
+	return respository.queryCategories(ctx, sqlTx, SQL, categoryId)
+}
+
+func (respository *CategoryRepositoryImpl) FindSubtree(ctx context.Context, tx Tx, categoryId int64) ([]domain.Category, error) {
+	sqlTx, err := unwrapSQLTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	SQL := `WITH RECURSIVE subtree(id, name, parent_id, version, depth) AS (
+		SELECT id, name, parent_id, version, 0 FROM category WHERE id = ?
+		UNION ALL
+		SELECT c.id, c.name, c.parent_id, c.version, s.depth + 1 FROM category c JOIN subtree s ON c.parent_id = s.id
+	)
+	SELECT id, name, parent_id, version FROM subtree ORDER BY depth, name, id`
+	categories, err := respository.queryCategories(ctx, sqlTx, SQL, categoryId)
+	if err == nil && len(categories) == 0 {
+		return nil, ErrCategoryNotFound
 	}
+	return categories, err
 }
 
-func (respository *CategoryRepositoryImpl) FindAll(ctx context.Context, tx *sql.Tx) []domain.Category {
-	SQL := "SELECT * FROM category"
-	resRows, err := tx.QueryContext(ctx, SQL)
-	helper.PanicfIfErr(err)
+func (respository *CategoryRepositoryImpl) queryCategories(ctx context.Context, sqlTx db.Queryer, SQL string, args ...interface{}) ([]domain.Category, error) {
+	resRows, err := sqlTx.QueryContext(ctx, respository.Dialect.Rebind(SQL), args...)
+	if err != nil {
+		return nil, err
+	}
 	defer resRows.Close()
 
 	var categories []domain.Category
 	for resRows.Next() {
-		category := domain.Category{}
-		err := resRows.Scan(&category.Id, &category.Name)
-		helper.PanicfIfErr(err)
+		category, err := scanCategory(resRows)
+		if err != nil {
+			return nil, err
+		}
 		categories = append(categories, category)
 	}
-	return categories
+	return categories, resRows.Err()
+}
+
+func scanCategory(resRows *sql.Rows) (domain.Category, error) {
+	category := domain.Category{}
+	var parentId sql.NullInt64
+	err := resRows.Scan(&category.Id, &category.Name, &parentId, &category.Version)
+	if parentId.Valid {
+		category.ParentId = &parentId.Int64
+	}
+	return category, err
+}
+
+func categoryWhere(query CategoryQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if query.ParentId != nil {
+		conditions = append(conditions, "parent_id = ?")
+		args = append(args, *query.ParentId)
+	}
+
+	if query.Name != "" {
+		pattern := escapeLike(strings.ToLower(query.Name)) + "%"
+		if query.NameMatch != NameMatchPrefix {
+			pattern = "%" + pattern
+		}
+		conditions = append(conditions, "LOWER(name) LIKE ? ESCAPE '!'")
+		args = append(args, pattern)
+	}
+
+	if query.After != nil {
+		condition, afterArgs := categoryAfter(*query.After, EffectiveSort(query.Sort))
+		conditions = append(conditions, condition)
+		args = append(args, afterArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// categoryAfter expands the row comparison (a, b) > (x, y) into
+// a > x OR (a = x AND b > y), flipping the operator for descending fields.
+func categoryAfter(after domain.Category, sort []SortField) (string, []interface{}) {
+	var alternatives []string
+	var args []interface{}
+	for i, field := range sort {
+		var terms []string
+		for _, previous := range sort[:i] {
+			terms = append(terms, previous.Field+" = ?")
+			args = append(args, categoryFieldValue(after, previous.Field))
+		}
+		operator := " > ?"
+		if field.Desc {
+			operator = " < ?"
+		}
+		terms = append(terms, field.Field+operator)
+		args = append(args, categoryFieldValue(after, field.Field))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+func categoryFieldValue(category domain.Category, field string) interface{} {
+	if field == "name" {
+		return category.Name
+	}
+	return category.Id
+}
+
+func categoryOrderBy(sort []SortField) string {
+	var terms []string
+	for _, field := range EffectiveSort(sort) {
+		term := field.Field
+		if field.Desc {
+			term += " DESC"
+		}
+		terms = append(terms, term)
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return replacer.Replace(value)
 }
@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rtanx/golang-restful-api/model/domain"
+)
+
+// CategoryRepositoryMemory keeps categories in a map and doubles as its own
+// TxManager. Transactions are serialized: Begin blocks until the previous
+// transaction is committed or rolled back, and Rollback restores the state
+// captured by Begin. Like CategoryRepositoryImpl, it only accepts the open
+// transactions its own Begin returned.
+type CategoryRepositoryMemory struct {
+	txMu sync.Mutex
+
+	mu         sync.RWMutex
+	categories map[int64]domain.Category
+	lastId     int64
+}
+
+func NewCategoryRepositoryMemory() *CategoryRepositoryMemory {
+	return &CategoryRepositoryMemory{
+		categories: map[int64]domain.Category{},
+	}
+}
+
+type memoryTx struct {
+	repository *CategoryRepositoryMemory
+	categories map[int64]domain.Category
+	lastId     int64
+	done       bool
+}
+
+func (repository *CategoryRepositoryMemory) Begin(ctx context.Context) (Tx, error) {
+	repository.txMu.Lock()
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	snapshot := make(map[int64]domain.Category, len(repository.categories))
+	for id, category := range repository.categories {
+		snapshot[id] = category
+	}
+	return &memoryTx{
+		repository: repository,
+		categories: snapshot,
+		lastId:     repository.lastId,
+	}, nil
+}
+
+func (tx *memoryTx) Commit() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+	tx.repository.txMu.Unlock()
+	return nil
+}
+
+func (tx *memoryTx) Rollback() error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+
+	tx.repository.mu.Lock()
+	tx.repository.categories = tx.categories
+	tx.repository.lastId = tx.lastId
+	tx.repository.mu.Unlock()
+
+	tx.repository.txMu.Unlock()
+	return nil
+}
+
+// checkTx returns ErrUnsupportedTx unless tx is an open transaction of this
+// repository.
+func (repository *CategoryRepositoryMemory) checkTx(tx Tx) error {
+	memTx, ok := tx.(*memoryTx)
+	if !ok || memTx == nil || memTx.repository != repository {
+		return ErrUnsupportedTx
+	}
+	if memTx.done {
+		return sql.ErrTxDone
+	}
+	return nil
+}
+
+func (repository *CategoryRepositoryMemory) Save(ctx context.Context, tx Tx, category domain.Category) (domain.Category, error) {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return category, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if repository.nameTaken(category) {
+		return category, ErrDuplicateCategoryName
+	}
+	repository.lastId++
+	category.Id = repository.lastId
+	category.Version = 1
+	repository.categories[category.Id] = category
+	return category, nil
+}
+
+func (repository *CategoryRepositoryMemory) Update(ctx context.Context, tx Tx, category domain.Category) (domain.Category, error) {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return category, err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	stored, ok := repository.categories[category.Id]
+	if !ok || stored.Version != category.Version {
+		return category, ErrCategoryVersion
+	}
+	if repository.nameTaken(category) {
+		return category, ErrDuplicateCategoryName
+	}
+	category.Version++
+	repository.categories[category.Id] = category
+	return category, nil
+}
+
+func (repository *CategoryRepositoryMemory) Delete(ctx context.Context, tx Tx, category domain.Category) error {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return err
+	}
+
+	repository.mu.Lock()
+	defer repository.mu.Unlock()
+
+	if stored, ok := repository.categories[category.Id]; !ok || stored.Version != category.Version {
+		return ErrCategoryVersion
+	}
+	delete(repository.categories, category.Id)
+	return nil
+}
+
+func (repository *CategoryRepositoryMemory) FindById(ctx context.Context, tx Tx, categoryId int64) (domain.Category, error) {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return domain.Category{}, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	category, ok := repository.categories[categoryId]
+	if !ok {
+		return domain.Category{}, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+func (repository *CategoryRepositoryMemory) FindByName(ctx context.Context, tx Tx, name string) (domain.Category, error) {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return domain.Category{}, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	for _, category := range repository.categories {
+		if strings.EqualFold(category.Name, name) {
+			return category, nil
+		}
+	}
+	return domain.Category{}, ErrCategoryNotFound
+}
+
+func (repository *CategoryRepositoryMemory) nameTaken(category domain.Category) bool {
+	for _, other := range repository.categories {
+		if other.Id != category.Id && strings.EqualFold(other.Name, category.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (repository *CategoryRepositoryMemory) FindAll(ctx context.Context, tx Tx, query CategoryQuery) ([]domain.Category, error) {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := repository.filter(query)
+	sort.Slice(categories, func(i, j int) bool {
+		return lessCategory(categories[i], categories[j], query.Sort)
+	})
+
+	if query.After != nil {
+		after := *query.After
+		categories = categories[sort.Search(len(categories), func(i int) bool {
+			return lessCategory(after, categories[i], query.Sort)
+		}):]
+	}
+
+	if query.Offset >= len(categories) {
+		return nil, nil
+	}
+	categories = categories[query.Offset:]
+	if query.Limit > 0 && query.Limit < len(categories) {
+		categories = categories[:query.Limit]
+	}
+	return categories, nil
+}
+
+func (repository *CategoryRepositoryMemory) Count(ctx context.Context, tx Tx, query CategoryQuery) (int64, error) {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(repository.filter(query))), nil
+}
+
+func (repository *CategoryRepositoryMemory) filter(query CategoryQuery) []domain.Category {
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	name := strings.ToLower(query.Name)
+	var categories []domain.Category
+	for _, category := range repository.categories {
+		if query.ParentId != nil && (category.ParentId == nil || *category.ParentId != *query.ParentId) {
+			continue
+		}
+		categoryName := strings.ToLower(category.Name)
+		if query.NameMatch == NameMatchPrefix && !strings.HasPrefix(categoryName, name) {
+			continue
+		}
+		if query.NameMatch != NameMatchPrefix && !strings.Contains(categoryName, name) {
+			continue
+		}
+		categories = append(categories, category)
+	}
+	return categories
+}
+
+func (repository *CategoryRepositoryMemory) FindAncestors(ctx context.Context, tx Tx, categoryId int64) ([]domain.Category, error) {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	var ancestors []domain.Category
+	category, ok := repository.categories[categoryId]
+	for ok && category.ParentId != nil && len(ancestors) <= len(repository.categories) {
+		category, ok = repository.categories[*category.ParentId]
+		if ok {
+			ancestors = append([]domain.Category{category}, ancestors...)
+		}
+	}
+	return ancestors, nil
+}
+
+func (repository *CategoryRepositoryMemory) FindSubtree(ctx context.Context, tx Tx, categoryId int64) ([]domain.Category, error) {
+	err := repository.checkTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	repository.mu.RLock()
+	defer repository.mu.RUnlock()
+
+	root, ok := repository.categories[categoryId]
+	if !ok {
+		return nil, ErrCategoryNotFound
+	}
+
+	subtree := []domain.Category{root}
+	level := []domain.Category{root}
+	for len(level) > 0 {
+		var next []domain.Category
+		for _, parent := range level {
+			for _, category := range repository.categories {
+				if category.ParentId != nil && *category.ParentId == parent.Id {
+					next = append(next, category)
+				}
+			}
+		}
+		sort.Slice(next, func(i, j int) bool {
+			return lessCategory(next[i], next[j], []SortField{{Field: "name"}})
+		})
+		subtree = append(subtree, next...)
+		level = next
+	}
+	return subtree, nil
+}
+
+func lessCategory(a domain.Category, b domain.Category, fields []SortField) bool {
+	for _, field := range fields {
+		var cmp int
+		switch field.Field {
+		case "id":
+			cmp = compareInt64(a.Id, b.Id)
+		case "name":
+			cmp = strings.Compare(a.Name, b.Name)
+		}
+		if field.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return a.Id < b.Id
+}
+
+func compareInt64(a int64, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
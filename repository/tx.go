@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/rtanx/golang-restful-api/db"
+	"github.com/rtanx/golang-restful-api/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrUnsupportedTx = errors.New("transaction is not supported by this repository")
+
+// Tx is the unit of work the service layer hands to repositories. Each
+// repository implementation only accepts the transactions created by its
+// matching TxManager.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+type TxManager interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+type SQLTxManager struct {
+	DB *sql.DB
+}
+
+func NewSQLTxManager(DB *sql.DB) TxManager {
+	return &SQLTxManager{DB: DB}
+}
+
+func (manager *SQLTxManager) Begin(ctx context.Context) (Tx, error) {
+	_, span := tracing.Start(ctx, "db.begin", trace.WithSpanKind(trace.SpanKindClient))
+	tx, err := manager.DB.BeginTx(ctx, nil)
+	tracing.RecordError(span, err)
+	span.End()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, ctx: ctx}, nil
+}
+
+// sqlTx traces its commit or rollback, and each statement it runs, as
+// children of the span that began it.
+type sqlTx struct {
+	*sql.Tx
+	ctx context.Context
+}
+
+func (tx *sqlTx) Commit() error {
+	_, span := tracing.Start(tx.ctx, "db.commit", trace.WithSpanKind(trace.SpanKindClient))
+	err := tx.Tx.Commit()
+	tracing.RecordError(span, err)
+	span.End()
+	return err
+}
+
+func (tx *sqlTx) Rollback() error {
+	_, span := tracing.Start(tx.ctx, "db.rollback", trace.WithSpanKind(trace.SpanKindClient))
+	err := tx.Tx.Rollback()
+	tracing.RecordError(span, err)
+	span.End()
+	return err
+}
+
+func (tx *sqlTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startStatement(ctx, "db.exec", query)
+	res, err := tx.Tx.ExecContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+	span.End()
+	return res, err
+}
+
+func (tx *sqlTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startStatement(ctx, "db.query", query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+	span.End()
+	return rows, err
+}
+
+func (tx *sqlTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startStatement(ctx, "db.query", query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	tracing.RecordError(span, row.Err())
+	span.End()
+	return row
+}
+
+func startStatement(ctx context.Context, name string, query string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(semconv.DBQueryText(query)))
+}
+
+// unwrapSQLTx also accepts a plain *sql.Tx, whose statements are not traced.
+func unwrapSQLTx(tx Tx) (db.Queryer, error) {
+	switch t := tx.(type) {
+	case *sqlTx:
+		if t != nil {
+			return t, nil
+		}
+	case *sql.Tx:
+		if t != nil {
+			return t, nil
+		}
+	}
+	return nil, ErrUnsupportedTx
+}
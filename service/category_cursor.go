@@ -0,0 +1,41 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/rtanx/golang-restful-api/exception"
+	"github.com/rtanx/golang-restful-api/model/domain"
+)
+
+// categoryCursor is the decoded form of the opaque cursor handed to clients.
+// It records the sort the cursor was issued for and the sort key values of
+// the last category on the page.
+type categoryCursor struct {
+	Sort string `json:"s"`
+	Id   int64  `json:"i"`
+	Name string `json:"n,omitempty"`
+}
+
+func encodeCategoryCursor(sort string, category domain.Category) string {
+	cursor := categoryCursor{Sort: sort, Id: category.Id, Name: category.Name}
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeCategoryCursor(value string, sort string) (domain.Category, error) {
+	invalid := exception.NewBadRequestError("cursor is invalid")
+
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return domain.Category{}, invalid
+	}
+	var cursor categoryCursor
+	if err = json.Unmarshal(payload, &cursor); err != nil {
+		return domain.Category{}, invalid
+	}
+	if cursor.Sort != sort {
+		return domain.Category{}, exception.NewBadRequestError("cursor was issued for a different sort")
+	}
+	return domain.Category{Id: cursor.Id, Name: cursor.Name}, nil
+}
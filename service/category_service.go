@@ -8,9 +8,13 @@ import (
 )
 
 type CategoryService interface {
-	Create(ctx context.Context, request webrequest.CategoryCreateRequest) webresponse.CategoryResponse
-	Update(ctx context.Context, request webrequest.CategoryUpdateRequest) webresponse.CategoryResponse
-	Delete(ctx context.Context, categoryId int64)
-	FindById(ctx context.Context, categoryId int64) webresponse.CategoryResponse
-	FindAll(ctx context.Context) []webresponse.CategoryResponse
+	Create(ctx context.Context, request webrequest.CategoryCreateRequest) (webresponse.CategoryResponse, error)
+	Update(ctx context.Context, request webrequest.CategoryUpdateRequest) (webresponse.CategoryResponse, error)
+	Delete(ctx context.Context, request webrequest.CategoryDeleteRequest) error
+	FindById(ctx context.Context, categoryId int64) (webresponse.CategoryResponse, error)
+	FindAll(ctx context.Context, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error)
+	Move(ctx context.Context, request webrequest.CategoryMoveRequest) (webresponse.CategoryResponse, error)
+	FindChildren(ctx context.Context, categoryId int64, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error)
+	FindAncestors(ctx context.Context, categoryId int64) ([]webresponse.CategoryResponse, error)
+	FindSubtree(ctx context.Context, categoryId int64) (webresponse.CategoryTreeResponse, error)
 }
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
-	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/rtanx/golang-restful-api/exception"
@@ -13,93 +15,461 @@ import (
 	"github.com/rtanx/golang-restful-api/repository"
 )
 
+const DefaultPageSize = 20
+
 type CategoryServiceImpl struct {
 	CategoryRepository repository.CategoryRepository
-	DB                 *sql.DB
+	TxManager          repository.TxManager
 	Validate           *validator.Validate
 }
 
-func NewCategoryService(categoryRepository repository.CategoryRepository, DB *sql.DB, validate *validator.Validate) CategoryService {
+func NewCategoryService(categoryRepository repository.CategoryRepository, txManager repository.TxManager, validate *validator.Validate) CategoryService {
 	return &CategoryServiceImpl{
 		CategoryRepository: categoryRepository,
-		DB:                 DB,
+		TxManager:          txManager,
 		Validate:           validate,
 	}
 }
 
-func (service *CategoryServiceImpl) Create(ctx context.Context, request webrequest.CategoryCreateRequest) webresponse.CategoryResponse {
-	err := service.Validate.Struct(request)
-	helper.PanicfIfErr(err)
+func (service *CategoryServiceImpl) Create(ctx context.Context, request webrequest.CategoryCreateRequest) (response webresponse.CategoryResponse, err error) {
+	err = service.Validate.Struct(request)
+	if err != nil {
+		return response, err
+	}
 
-	tx, err := service.DB.Begin()
-	helper.PanicfIfErr(err)
+	tx, err := service.TxManager.Begin(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer helper.CommitOrRollback(tx, &err)
 
-	defer helper.CommitOrRollback(tx)
+	err = service.checkParent(ctx, tx, 0, request.ParentId)
+	if err != nil {
+		return response, err
+	}
+	err = service.checkName(ctx, tx, 0, request.Name)
+	if err != nil {
+		return response, err
+	}
 
 	category := domain.Category{
-		Name: request.Name,
+		Name:     request.Name,
+		ParentId: request.ParentId,
+	}
+	category, err = service.CategoryRepository.Save(ctx, tx, category)
+	if errors.Is(err, repository.ErrDuplicateCategoryName) {
+		return response, nameConflict(category.Name, err)
 	}
-	category = service.CategoryRepository.Save(ctx, tx, category)
-	return helper.ToCategoryResponse(category)
+	if err != nil {
+		return response, err
+	}
+	return helper.ToCategoryResponse(category), nil
 }
 
-func (service *CategoryServiceImpl) Update(ctx context.Context, request webrequest.CategoryUpdateRequest) webresponse.CategoryResponse {
-	err := service.Validate.Struct(request)
-	helper.PanicfIfErr(err)
+func (service *CategoryServiceImpl) Update(ctx context.Context, request webrequest.CategoryUpdateRequest) (response webresponse.CategoryResponse, err error) {
+	err = service.Validate.Struct(request)
+	if err != nil {
+		return response, err
+	}
 
-	tx, err := service.DB.Begin()
-	helper.PanicfIfErr(err)
+	tx, err := service.TxManager.Begin(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer helper.CommitOrRollback(tx, &err)
 
-	defer helper.CommitOrRollback(tx)
+	category, err := service.findById(ctx, tx, request.Id)
+	if err != nil {
+		return response, err
+	}
+	err = checkVersion(category, request.IfMatch)
+	if err != nil {
+		return response, err
+	}
 
-	category, err := service.CategoryRepository.FindById(ctx, tx, request.Id)
+	err = service.checkName(ctx, tx, category.Id, request.Name)
 	if err != nil {
-		panic(exception.NewNotFoundError(err.Error()))
+		return response, err
 	}
 
 	category.Name = request.Name
+	if request.ParentId != nil {
+		err = service.checkParent(ctx, tx, category.Id, request.ParentId)
+		if err != nil {
+			return response, err
+		}
+		category.ParentId = request.ParentId
+	}
 
-	category = service.CategoryRepository.Update(ctx, tx, category)
-	return helper.ToCategoryResponse(category)
+	category, err = service.CategoryRepository.Update(ctx, tx, category)
+	if errors.Is(err, repository.ErrDuplicateCategoryName) {
+		return response, nameConflict(category.Name, err)
+	}
+	if errors.Is(err, repository.ErrCategoryVersion) {
+		return response, versionConflict(request.IfMatch, err)
+	}
+	if err != nil {
+		return response, err
+	}
+	return helper.ToCategoryResponse(category), nil
 }
 
-func (service *CategoryServiceImpl) Delete(ctx context.Context, categoryId int64) {
-	tx, err := service.DB.Begin()
-	helper.PanicfIfErr(err)
+func (service *CategoryServiceImpl) Delete(ctx context.Context, request webrequest.CategoryDeleteRequest) (err error) {
+	err = service.Validate.Struct(request)
+	if err != nil {
+		return err
+	}
 
-	category, err := service.CategoryRepository.FindById(ctx, tx, categoryId)
+	tx, err := service.TxManager.Begin(ctx)
 	if err != nil {
-		panic(exception.NewNotFoundError(err.Error()))
+		return err
 	}
+	defer helper.CommitOrRollback(tx, &err)
 
-	service.CategoryRepository.Delete(ctx, tx, category)
+	category, err := service.findById(ctx, tx, request.Id)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(category, request.IfMatch)
+	if err != nil {
+		return err
+	}
 
-	defer helper.CommitOrRollback(tx)
+	children, err := service.CategoryRepository.Count(ctx, tx, repository.CategoryQuery{ParentId: &category.Id})
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return exception.NewConflictError("category still has child categories, move or delete them first")
+	}
 
-	service.CategoryRepository.Delete(ctx, tx, category)
+	err = service.CategoryRepository.Delete(ctx, tx, category)
+	if errors.Is(err, repository.ErrCategoryVersion) {
+		return versionConflict(request.IfMatch, err)
+	}
+	return err
 }
 
-func (service *CategoryServiceImpl) FindById(ctx context.Context, categoryId int64) webresponse.CategoryResponse {
-	tx, err := service.DB.Begin()
-	helper.PanicfIfErr(err)
+func (service *CategoryServiceImpl) Move(ctx context.Context, request webrequest.CategoryMoveRequest) (response webresponse.CategoryResponse, err error) {
+	err = service.Validate.Struct(request)
+	if err != nil {
+		return response, err
+	}
 
-	defer helper.CommitOrRollback(tx)
+	tx, err := service.TxManager.Begin(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer helper.CommitOrRollback(tx, &err)
 
-	category, err := service.CategoryRepository.FindById(ctx, tx, categoryId)
+	category, err := service.findById(ctx, tx, request.Id)
+	if err != nil {
+		return response, err
+	}
+	err = checkVersion(category, request.IfMatch)
+	if err != nil {
+		return response, err
+	}
+
+	err = service.checkParent(ctx, tx, category.Id, request.ParentId)
+	if err != nil {
+		return response, err
+	}
+	category.ParentId = request.ParentId
+
+	category, err = service.CategoryRepository.Update(ctx, tx, category)
+	if errors.Is(err, repository.ErrCategoryVersion) {
+		return response, versionConflict(request.IfMatch, err)
+	}
+	if err != nil {
+		return response, err
+	}
+	return helper.ToCategoryResponse(category), nil
+}
+
+func (service *CategoryServiceImpl) FindById(ctx context.Context, categoryId int64) (response webresponse.CategoryResponse, err error) {
+	tx, err := service.TxManager.Begin(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer helper.CommitOrRollback(tx, &err)
+
+	category, err := service.findById(ctx, tx, categoryId)
+	if err != nil {
+		return response, err
+	}
+
+	return helper.ToCategoryResponse(category), nil
+}
+
+// FindAll pages with limit/offset unless request.Cursor is set, in which case
+// it continues after the category the cursor points at. Both modes return a
+// cursor for the next page.
+func (service *CategoryServiceImpl) FindAll(ctx context.Context, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error) {
+	return service.list(ctx, request, nil)
+}
+
+func (service *CategoryServiceImpl) FindChildren(ctx context.Context, categoryId int64, request webrequest.CategoryListRequest) (webresponse.CategoryListResponse, error) {
+	return service.list(ctx, request, &categoryId)
+}
+
+func (service *CategoryServiceImpl) FindAncestors(ctx context.Context, categoryId int64) (responses []webresponse.CategoryResponse, err error) {
+	tx, err := service.TxManager.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer helper.CommitOrRollback(tx, &err)
+
+	_, err = service.findById(ctx, tx, categoryId)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := service.CategoryRepository.FindAncestors(ctx, tx, categoryId)
+	if err != nil {
+		return nil, err
+	}
+	return helper.ToCategoriesResponse(ancestors), nil
+}
+
+func (service *CategoryServiceImpl) FindSubtree(ctx context.Context, categoryId int64) (response webresponse.CategoryTreeResponse, err error) {
+	tx, err := service.TxManager.Begin(ctx)
 	if err != nil {
-		panic(exception.NewNotFoundError(err.Error()))
+		return response, err
 	}
+	defer helper.CommitOrRollback(tx, &err)
 
-	return helper.ToCategoryResponse(category)
+	subtree, err := service.CategoryRepository.FindSubtree(ctx, tx, categoryId)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		return response, exception.WrapNotFoundError(err)
+	}
+	if err != nil {
+		return response, err
+	}
+	return helper.ToCategoryTreeResponse(subtree), nil
 }
 
-func (service *CategoryServiceImpl) FindAll(ctx context.Context) []webresponse.CategoryResponse {
-	tx, err := service.DB.Begin()
-	helper.PanicfIfErr(err)
+func (service *CategoryServiceImpl) list(ctx context.Context, request webrequest.CategoryListRequest, parentId *int64) (response webresponse.CategoryListResponse, err error) {
+	err = service.Validate.Struct(request)
+	if err != nil {
+		return response, err
+	}
 
-	defer helper.CommitOrRollback(tx)
+	query, err := toCategoryQuery(request)
+	if err != nil {
+		return response, err
+	}
+	query.ParentId = parentId
 
-	categories := service.CategoryRepository.FindAll(ctx, tx)
+	tx, err := service.TxManager.Begin(ctx)
+	if err != nil {
+		return response, err
+	}
+	defer helper.CommitOrRollback(tx, &err)
+
+	if parentId != nil {
+		_, err = service.findById(ctx, tx, *parentId)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	if query.After != nil {
+		size := query.Limit
+		query.Limit++
 
-	return helper.ToCategoriesResponse(categories)
+		categories, err := service.CategoryRepository.FindAll(ctx, tx, query)
+		if err != nil {
+			return response, err
+		}
+
+		meta := webresponse.CursorMeta{Size: size}
+		if len(categories) > size {
+			categories = categories[:size]
+			meta.HasMore = true
+			meta.NextCursor = encodeCategoryCursor(request.Sort, categories[size-1])
+		}
+		response.Categories = helper.ToCategoriesResponse(categories)
+		response.Meta = meta
+		return response, nil
+	}
+
+	categories, err := service.CategoryRepository.FindAll(ctx, tx, query)
+	if err != nil {
+		return response, err
+	}
+	total, err := service.CategoryRepository.Count(ctx, tx, query)
+	if err != nil {
+		return response, err
+	}
+
+	meta := webresponse.PageMeta{
+		Page:       query.Offset/query.Limit + 1,
+		Size:       query.Limit,
+		Offset:     query.Offset,
+		TotalItems: total,
+		TotalPages: (total + int64(query.Limit) - 1) / int64(query.Limit),
+	}
+	if len(categories) > 0 && int64(query.Offset+len(categories)) < total {
+		meta.NextCursor = encodeCategoryCursor(request.Sort, categories[len(categories)-1])
+	}
+	response.Categories = helper.ToCategoriesResponse(categories)
+	response.Meta = meta
+	return response, nil
+}
+
+// checkParent verifies that parentId can become the parent of categoryId: it
+// must exist and must not be the category itself or one of its descendants.
+// A zero categoryId is used for categories that do not exist yet.
+func (service *CategoryServiceImpl) checkParent(ctx context.Context, tx repository.Tx, categoryId int64, parentId *int64) error {
+	if parentId == nil {
+		return nil
+	}
+	if *parentId == categoryId {
+		return exception.NewBadRequestError("category cannot be its own parent")
+	}
+
+	_, err := service.CategoryRepository.FindById(ctx, tx, *parentId)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		return exception.NewBadRequestError(fmt.Sprintf("parent category %d is not found", *parentId))
+	}
+	if err != nil {
+		return err
+	}
+
+	if categoryId == 0 {
+		return nil
+	}
+	ancestors, err := service.CategoryRepository.FindAncestors(ctx, tx, *parentId)
+	if err != nil {
+		return err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.Id == categoryId {
+			return exception.NewBadRequestError("category cannot be moved under one of its descendants")
+		}
+	}
+	return nil
+}
+
+// checkName reports a conflict when another category already uses name,
+// ignoring case. The unique index on category catches concurrent inserts
+// that slip past this check.
+func (service *CategoryServiceImpl) checkName(ctx context.Context, tx repository.Tx, categoryId int64, name string) error {
+	existing, err := service.CategoryRepository.FindByName(ctx, tx, name)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Id != categoryId {
+		return nameConflict(name, repository.ErrDuplicateCategoryName)
+	}
+	return nil
+}
+
+func nameConflict(name string, err error) exception.ConflictError {
+	return exception.WrapConflictError(fmt.Sprintf("category named %q already exists", name), err)
+}
+
+// checkVersion fails with exception.PreconditionFailedError unless
+// ifMatch is empty or contains the version of category.
+func checkVersion(category domain.Category, ifMatch []int64) error {
+	if len(ifMatch) == 0 {
+		return nil
+	}
+	for _, version := range ifMatch {
+		if version == category.Version {
+			return nil
+		}
+	}
+	return exception.NewPreconditionFailedError(fmt.Sprintf("category %d is at version %d", category.Id, category.Version))
+}
+
+// versionConflict translates repository.ErrCategoryVersion, which means that
+// a concurrent transaction changed the category after it was read. Callers
+// that sent If-Match get 412, as their precondition no longer holds; others
+// get 409.
+func versionConflict(ifMatch []int64, err error) error {
+	if len(ifMatch) > 0 {
+		return exception.WrapPreconditionFailedError("category has been modified concurrently", err)
+	}
+	return exception.WrapConflictError("category has been modified concurrently", err)
+}
+
+// findById translates repository.ErrCategoryNotFound into an
+// exception.NotFoundError so callers can tell it apart from storage failures.
+func (service *CategoryServiceImpl) findById(ctx context.Context, tx repository.Tx, categoryId int64) (domain.Category, error) {
+	category, err := service.CategoryRepository.FindById(ctx, tx, categoryId)
+	if errors.Is(err, repository.ErrCategoryNotFound) {
+		return category, exception.WrapNotFoundError(err)
+	}
+	return category, err
+}
+
+// toCategoryQuery resolves the paging defaults. limit/offset take precedence
+// over page/size when both are given, and a cursor cannot be combined with
+// either page or offset.
+func toCategoryQuery(request webrequest.CategoryListRequest) (repository.CategoryQuery, error) {
+	query := repository.CategoryQuery{
+		Name:      request.Name,
+		NameMatch: request.NameMatch,
+		Limit:     DefaultPageSize,
+	}
+
+	if request.Cursor != "" {
+		if request.Page > 0 || request.Offset > 0 {
+			return query, exception.NewBadRequestError("cursor cannot be combined with page or offset")
+		}
+		after, err := decodeCategoryCursor(request.Cursor, request.Sort)
+		if err != nil {
+			return query, err
+		}
+		query.After = &after
+	}
+
+	if request.Limit > 0 || request.Offset > 0 {
+		if request.Limit > 0 {
+			query.Limit = request.Limit
+		}
+		query.Offset = request.Offset
+	} else {
+		if request.Size > 0 {
+			query.Limit = request.Size
+		}
+		if request.Page > 1 {
+			query.Offset = (request.Page - 1) * query.Limit
+		}
+	}
+
+	sort, err := parseSort(request.Sort)
+	if err != nil {
+		return query, err
+	}
+	query.Sort = sort
+	return query, nil
+}
+
+// parseSort turns "name,-id" into sort fields; a leading "-" sorts descending.
+func parseSort(value string) ([]repository.SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []repository.SortField
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		field := repository.SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+
+		known := false
+		for _, allowed := range repository.CategorySortFields {
+			known = known || field.Field == allowed
+		}
+		if !known {
+			return nil, exception.NewBadRequestError(fmt.Sprintf("cannot sort by %q, expected one of %s", part, strings.Join(repository.CategorySortFields, ", ")))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
 }
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+
+	webrequest "github.com/rtanx/golang-restful-api/model/web/request"
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedCategoryService records a span for each call to CategoryService.
+type TracedCategoryService struct {
+	CategoryService CategoryService
+}
+
+func NewTracedCategoryService(categoryService CategoryService) CategoryService {
+	return &TracedCategoryService{CategoryService: categoryService}
+}
+
+func (service *TracedCategoryService) Create(ctx context.Context, request webrequest.CategoryCreateRequest) (response webresponse.CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Create")
+	defer endSpan(span, &err)
+	return service.CategoryService.Create(ctx, request)
+}
+
+func (service *TracedCategoryService) Update(ctx context.Context, request webrequest.CategoryUpdateRequest) (response webresponse.CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Update")
+	span.SetAttributes(attribute.Int64("category.id", request.Id))
+	defer endSpan(span, &err)
+	return service.CategoryService.Update(ctx, request)
+}
+
+func (service *TracedCategoryService) Delete(ctx context.Context, request webrequest.CategoryDeleteRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Delete")
+	span.SetAttributes(attribute.Int64("category.id", request.Id))
+	defer endSpan(span, &err)
+	return service.CategoryService.Delete(ctx, request)
+}
+
+func (service *TracedCategoryService) FindById(ctx context.Context, categoryId int64) (response webresponse.CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.FindById")
+	span.SetAttributes(attribute.Int64("category.id", categoryId))
+	defer endSpan(span, &err)
+	return service.CategoryService.FindById(ctx, categoryId)
+}
+
+func (service *TracedCategoryService) FindAll(ctx context.Context, request webrequest.CategoryListRequest) (response webresponse.CategoryListResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.FindAll")
+	defer endSpan(span, &err)
+	return service.CategoryService.FindAll(ctx, request)
+}
+
+func (service *TracedCategoryService) Move(ctx context.Context, request webrequest.CategoryMoveRequest) (response webresponse.CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.Move")
+	span.SetAttributes(attribute.Int64("category.id", request.Id))
+	defer endSpan(span, &err)
+	return service.CategoryService.Move(ctx, request)
+}
+
+func (service *TracedCategoryService) FindChildren(ctx context.Context, categoryId int64, request webrequest.CategoryListRequest) (response webresponse.CategoryListResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.FindChildren")
+	span.SetAttributes(attribute.Int64("category.id", categoryId))
+	defer endSpan(span, &err)
+	return service.CategoryService.FindChildren(ctx, categoryId, request)
+}
+
+func (service *TracedCategoryService) FindAncestors(ctx context.Context, categoryId int64) (responses []webresponse.CategoryResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.FindAncestors")
+	span.SetAttributes(attribute.Int64("category.id", categoryId))
+	defer endSpan(span, &err)
+	return service.CategoryService.FindAncestors(ctx, categoryId)
+}
+
+func (service *TracedCategoryService) FindSubtree(ctx context.Context, categoryId int64) (response webresponse.CategoryTreeResponse, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.FindSubtree")
+	span.SetAttributes(attribute.Int64("category.id", categoryId))
+	defer endSpan(span, &err)
+	return service.CategoryService.FindSubtree(ctx, categoryId)
+}
+
+func endSpan(span trace.Span, err *error) {
+	tracing.RecordError(span, *err)
+	span.End()
+}
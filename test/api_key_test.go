@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func testKeyStore(t *testing.T, keyStore auth.KeyStore) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	key, plaintext, err := auth.CreateKey(ctx, keyStore, "ci", []string{auth.ScopeCategoriesRead, auth.ScopeCategoriesWrite}, nil)
+	assert.Nil(t, err)
+	assert.NotEqual(t, plaintext, key.Hash)
+	assert.Equal(t, auth.HashKey(plaintext), key.Hash)
+
+	authenticated, err := auth.Authenticate(ctx, keyStore, plaintext, now)
+	assert.Nil(t, err)
+	assert.Equal(t, key.Id, authenticated.Id)
+	assert.Equal(t, "ci", authenticated.Name)
+	assert.Equal(t, []string{auth.ScopeCategoriesRead, auth.ScopeCategoriesWrite}, authenticated.Scopes)
+
+	stored, err := keyStore.FindByHash(ctx, key.Hash)
+	assert.Nil(t, err)
+	assert.NotNil(t, stored.LastUsedAt)
+
+	_, err = auth.Authenticate(ctx, keyStore, plaintext+"x", now)
+	assert.Equal(t, auth.ErrKeyNotFound, err)
+	_, err = auth.Authenticate(ctx, keyStore, "", now)
+	assert.Equal(t, auth.ErrKeyNotFound, err)
+
+	expiresAt := now.Add(time.Hour)
+	_, expiring, err := auth.CreateKey(ctx, keyStore, "temporary", nil, &expiresAt)
+	assert.Nil(t, err)
+	_, err = auth.Authenticate(ctx, keyStore, expiring, now)
+	assert.Nil(t, err)
+	_, err = auth.Authenticate(ctx, keyStore, expiring, now.Add(2*time.Hour))
+	assert.Equal(t, auth.ErrKeyExpired, err)
+
+	err = keyStore.Revoke(ctx, key.Id, now)
+	assert.Nil(t, err)
+	_, err = auth.Authenticate(ctx, keyStore, plaintext, now.Add(time.Second))
+	assert.Equal(t, auth.ErrKeyRevoked, err)
+	assert.Equal(t, auth.ErrKeyNotFound, keyStore.Revoke(ctx, 10000, now))
+
+	keys, err := keyStore.FindAll(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+
+	ensured, err := auth.EnsureKey(ctx, keyStore, "default", "RAHASIA", nil)
+	assert.Nil(t, err)
+	again, err := auth.EnsureKey(ctx, keyStore, "default", "RAHASIA", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, ensured.Id, again.Id)
+}
+
+func TestSQLKeyStore(t *testing.T) {
+	DB := newTestDB()
+	DB.Exec("DELETE FROM api_key")
+
+	testKeyStore(t, auth.NewSQLKeyStore(DB, testDialect))
+}
+
+func TestMemoryKeyStore(t *testing.T) {
+	testKeyStore(t, auth.NewMemoryKeyStore())
+}
+
+func TestAuthMiddlewareKeyInContext(t *testing.T) {
+	keyStore := auth.NewMemoryKeyStore()
+	_, plaintext, err := auth.CreateKey(context.Background(), keyStore, "reporting", nil, nil)
+	assert.Nil(t, err)
+
+	handler := middleware.NewAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := auth.KeyFromContext(r.Context())
+		assert.True(t, ok)
+		fmt.Fprint(w, key.Name)
+	}), keyStore, nil, nil)
+
+	for header, status := range map[string]int{plaintext: 200, "RAHASIA": 401, "": 401} {
+		request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+		request.Header.Add("X-API-KEY", header)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		assert.Equal(t, status, recorder.Code)
+		if status == 200 {
+			assert.Equal(t, "reporting", recorder.Body.String())
+		}
+	}
+}
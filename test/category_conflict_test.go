@@ -0,0 +1,58 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/model/domain"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCategoryNameConflict(t *testing.T, router http.Handler) {
+	gadget := createCategory(t, router, "Gadget", nil)
+	computer := createCategory(t, router, "Computer", nil)
+
+	status, resBody := doJSON(router, http.MethodPost, "/api/categories", `{"name": "gADGET"}`)
+	assert.Equal(t, 409, status)
+	assert.Equal(t, "Conflict", resBody["status"])
+	assert.Equal(t, `category named "gADGET" already exists`, resBody["data"])
+
+	status, _ = doJSON(router, http.MethodPut, fmt.Sprintf("/api/categories/%d", computer), `{"name": "gadget"}`)
+	assert.Equal(t, 409, status)
+
+	status, resBody = doJSON(router, http.MethodPut, fmt.Sprintf("/api/categories/%d", gadget), `{"name": "GADGET"}`)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "GADGET", resBody["data"].(map[string]interface{})["name"])
+}
+
+func TestCategoryNameConflict(t *testing.T) {
+	DB := newTestDB()
+	truncateCategory(DB)
+
+	testCategoryNameConflict(t, setUpRouter(DB))
+}
+
+func TestMemoryCategoryNameConflict(t *testing.T) {
+	testCategoryNameConflict(t, setUpMemoryRouter(repository.NewCategoryRepositoryMemory()))
+}
+
+func TestCategoryNameUniqueIndex(t *testing.T) {
+	DB := newTestDB()
+	truncateCategory(DB)
+
+	ctx := context.Background()
+	cr := repository.NewCategoryRepository(testDialect)
+
+	tx, _ := DB.Begin()
+	_, err := cr.Save(ctx, tx, domain.Category{Name: "Gadget"})
+	assert.Nil(t, err)
+	tx.Commit()
+
+	tx, _ = DB.Begin()
+	_, err = cr.Save(ctx, tx, domain.Category{Name: "GADGET"})
+	tx.Rollback()
+	assert.Equal(t, repository.ErrDuplicateCategoryName, err)
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/controller"
+	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/rtanx/golang-restful-api/model/domain"
+	"github.com/rtanx/golang-restful-api/protocol"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/rtanx/golang-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func setUpConditionalRouter(categoryRepository repository.CategoryRepository, txManager repository.TxManager) http.Handler {
+	categoryService := service.NewCategoryService(categoryRepository, txManager, helper.NewValidator())
+	categoryController := controller.NewCategoryController(categoryService, config.ConcurrencyConfig{RequireIfMatch: true})
+	return middleware.NewAuthMiddleware(app.NewRouter(categoryController, nil), newTestKeyStore(), nil, nil)
+}
+
+func doConditional(router http.Handler, method string, path string, body string, header string, value string) *http.Response {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	if header != "" {
+		request.Header.Add(header, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder.Result()
+}
+
+func testCategoryETag(t *testing.T, router http.Handler) {
+	created := doConditional(router, http.MethodPost, "/api/categories", `{"name": "Gadget"}`, "", "")
+	assert.Equal(t, 200, created.StatusCode)
+	assert.Equal(t, `"1"`, created.Header.Get("ETag"))
+	path := "/api/categories/1"
+
+	response := doConditional(router, http.MethodGet, path, "", "", "")
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"1"`, response.Header.Get("ETag"))
+
+	response = doConditional(router, http.MethodGet, path, "", "If-None-Match", `W/"1"`)
+	assert.Equal(t, http.StatusNotModified, response.StatusCode)
+	assert.Equal(t, `"1"`, response.Header.Get("ETag"))
+	response = doConditional(router, http.MethodGet, path, "", "If-None-Match", `"0", "2"`)
+	assert.Equal(t, 200, response.StatusCode)
+
+	response = doConditional(router, http.MethodPut, path, `{"name": "Gadgets"}`, "", "")
+	assert.Equal(t, http.StatusPreconditionRequired, response.StatusCode)
+
+	response = doConditional(router, http.MethodPut, path, `{"name": "Gadgets"}`, "If-Match", `"1"`)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"2"`, response.Header.Get("ETag"))
+
+	// The second admin still holds version 1.
+	response = doConditional(router, http.MethodPut, path, `{"name": "Gizmo"}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+	response = doConditional(router, http.MethodPut, path, `{"name": "Gizmo"}`, "If-Match", `W/"2"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+	response = doConditional(router, http.MethodDelete, path, "", "If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+	response = doConditional(router, http.MethodDelete, path, "", "", "")
+	assert.Equal(t, http.StatusPreconditionRequired, response.StatusCode)
+
+	response = doConditional(router, http.MethodGet, path, "", "If-None-Match", `"1"`)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"2"`, response.Header.Get("ETag"))
+
+	response = doConditional(router, http.MethodPut, path, `{"name": "Gizmo"}`, "If-Match", `*`)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"3"`, response.Header.Get("ETag"))
+
+	move := path + "/move"
+	response = doConditional(router, http.MethodPost, move, `{"parent_id": null}`, "", "")
+	assert.Equal(t, http.StatusPreconditionRequired, response.StatusCode)
+	response = doConditional(router, http.MethodPost, move, `{"parent_id": null}`, "If-Match", `"2"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+	response = doConditional(router, http.MethodPost, move, `{"parent_id": null}`, "If-Match", `"3"`)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"4"`, response.Header.Get("ETag"))
+
+	response = doConditional(router, http.MethodDelete, path, "", "If-Match", `"3", "4"`)
+	assert.Equal(t, 200, response.StatusCode)
+	response = doConditional(router, http.MethodGet, path, "", "", "")
+	assert.Equal(t, 404, response.StatusCode)
+}
+
+func TestCategoryETag(t *testing.T) {
+	DB := newTestDB()
+	truncateCategory(DB)
+
+	testCategoryETag(t, setUpConditionalRouter(repository.NewCategoryRepository(testDialect), repository.NewSQLTxManager(DB)))
+}
+
+func TestMemoryCategoryETag(t *testing.T) {
+	categoryRepository := repository.NewCategoryRepositoryMemory()
+	testCategoryETag(t, setUpConditionalRouter(categoryRepository, categoryRepository))
+}
+
+func TestCategoryIfMatchOptional(t *testing.T) {
+	router := setUpMemoryRouter(repository.NewCategoryRepositoryMemory())
+	createCategory(t, router, "Gadget", nil)
+
+	response := doConditional(router, http.MethodPut, "/api/categories/1", `{"name": "Gadgets"}`, "", "")
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"2"`, response.Header.Get("ETag"))
+	response = doConditional(router, http.MethodPost, "/api/categories/1/move", `{"parent_id": null}`, "", "")
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, `"3"`, response.Header.Get("ETag"))
+
+	response = doConditional(router, http.MethodPut, "/api/categories/1", `{"name": "Gizmo"}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+}
+
+// TestCategoryVersionRepository checks that the repository itself refuses a
+// stale version, which catches updates that race between the service's read
+// and write.
+func TestCategoryVersionRepository(t *testing.T) {
+	DB := newTestDB()
+	truncateCategory(DB)
+
+	testCategoryVersionRepository(t, repository.NewCategoryRepository(testDialect), repository.NewSQLTxManager(DB))
+}
+
+func TestMemoryCategoryVersionRepository(t *testing.T) {
+	categoryRepository := repository.NewCategoryRepositoryMemory()
+	testCategoryVersionRepository(t, categoryRepository, categoryRepository)
+
+	// Like the SQL repository, the memory one only accepts its own
+	// transactions.
+	ctx := context.Background()
+	_, err := categoryRepository.Save(ctx, nil, domain.Category{Name: "Gadget"})
+	assert.Equal(t, repository.ErrUnsupportedTx, err)
+	other := repository.NewCategoryRepositoryMemory()
+	tx, _ := other.Begin(ctx)
+	_, err = categoryRepository.FindById(ctx, tx, 1)
+	assert.Equal(t, repository.ErrUnsupportedTx, err)
+	tx.Commit()
+	tx, _ = categoryRepository.Begin(ctx)
+	tx.Commit()
+	_, err = categoryRepository.FindAll(ctx, tx, repository.CategoryQuery{})
+	assert.Equal(t, sql.ErrTxDone, err)
+}
+
+func testCategoryVersionRepository(t *testing.T, cr repository.CategoryRepository, txManager repository.TxManager) {
+	ctx := context.Background()
+
+	tx, err := txManager.Begin(ctx)
+	assert.Nil(t, err)
+	saved, err := cr.Save(ctx, tx, domain.Category{Name: "Gadget"})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), saved.Version)
+
+	updated, err := cr.Update(ctx, tx, domain.Category{Id: saved.Id, Name: "Gadgets", Version: saved.Version})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+
+	_, err = cr.Update(ctx, tx, domain.Category{Id: saved.Id, Name: "Gizmo", Version: saved.Version})
+	assert.Equal(t, repository.ErrCategoryVersion, err)
+	assert.Equal(t, repository.ErrCategoryVersion, cr.Delete(ctx, tx, saved))
+
+	found, err := cr.FindById(ctx, tx, saved.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, updated, found)
+	assert.Nil(t, cr.Delete(ctx, tx, found))
+
+	// A row that is gone is indistinguishable from a stale version.
+	assert.Equal(t, repository.ErrCategoryVersion, cr.Delete(ctx, tx, found))
+	_, err = cr.Update(ctx, tx, found)
+	assert.Equal(t, repository.ErrCategoryVersion, err)
+	assert.Nil(t, tx.Commit())
+}
+
+func TestETagHelpers(t *testing.T) {
+	assert.Equal(t, `"2"`, protocol.ETag(2))
+	versions, any := protocol.ParseETags(`"1", W/"2", *`, false)
+	assert.True(t, any)
+	assert.Nil(t, versions)
+	versions, _ = protocol.ParseETags(`"1", W/"2", bogus`, false)
+	assert.Equal(t, []int64{1, 0, 0}, versions)
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// doJSON sends If-Match: * so that updates, moves and deletes pass the
+// default require_if_match; the ETag tests cover real versions.
+func doJSON(router http.Handler, method string, path string, body string) (int, map[string]interface{}) {
+	url := fmt.Sprintf("http://%s:%d%s", HOST, PORT, path)
+
+	var requestBody io.Reader
+	if body != "" {
+		requestBody = strings.NewReader(body)
+	}
+	request := httptest.NewRequest(method, url, requestBody)
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	request.Header.Add("If-Match", "*")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	resp := recorder.Result()
+	resBodyByte, _ := io.ReadAll(resp.Body)
+	var resBody map[string]interface{}
+	json.Unmarshal(resBodyByte, &resBody)
+	return resp.StatusCode, resBody
+}
+
+func createCategory(t *testing.T, router http.Handler, name string, parentId interface{}) int64 {
+	body, _ := json.Marshal(map[string]interface{}{"name": name, "parent_id": parentId})
+	status, resBody := doJSON(router, http.MethodPost, "/api/categories", string(body))
+	assert.Equal(t, 200, status, resBody)
+	return int64(resBody["data"].(map[string]interface{})["id"].(float64))
+}
+
+func names(data interface{}) []string {
+	var result []string
+	for _, item := range data.([]interface{}) {
+		result = append(result, item.(map[string]interface{})["name"].(string))
+	}
+	return result
+}
+
+func testCategoryHierarchy(t *testing.T, router http.Handler) {
+	electronics := createCategory(t, router, "Electronics", nil)
+	phones := createCategory(t, router, "Phones", electronics)
+	accessories := createCategory(t, router, "Accessories", phones)
+	laptops := createCategory(t, router, "Laptops", electronics)
+
+	status, resBody := doJSON(router, http.MethodGet, fmt.Sprintf("/api/categories/%d/ancestors", accessories), "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, []string{"Electronics", "Phones"}, names(resBody["data"]))
+
+	status, resBody = doJSON(router, http.MethodGet, fmt.Sprintf("/api/categories/%d/children", electronics), "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, []string{"Phones", "Laptops"}, names(resBody["data"]))
+
+	status, resBody = doJSON(router, http.MethodGet, fmt.Sprintf("/api/categories/%d/subtree", electronics), "")
+	assert.Equal(t, 200, status)
+	tree := resBody["data"].(map[string]interface{})
+	assert.Equal(t, "Electronics", tree["name"])
+	assert.Equal(t, []string{"Laptops", "Phones"}, names(tree["children"]))
+	phonesNode := tree["children"].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, []string{"Accessories"}, names(phonesNode["children"]))
+
+	// Electronics cannot go under its own grandchild, nor under itself.
+	status, _ = doJSON(router, http.MethodPost, fmt.Sprintf("/api/categories/%d/move", electronics), fmt.Sprintf(`{"parent_id": %d}`, accessories))
+	assert.Equal(t, 400, status)
+	status, _ = doJSON(router, http.MethodPut, fmt.Sprintf("/api/categories/%d", electronics), fmt.Sprintf(`{"name": "Electronics", "parent_id": %d}`, electronics))
+	assert.Equal(t, 400, status)
+	status, _ = doJSON(router, http.MethodPost, "/api/categories", `{"name": "Orphan", "parent_id": 10000}`)
+	assert.Equal(t, 400, status)
+
+	status, resBody = doJSON(router, http.MethodPost, fmt.Sprintf("/api/categories/%d/move", accessories), fmt.Sprintf(`{"parent_id": %d}`, laptops))
+	assert.Equal(t, 200, status)
+	assert.Equal(t, float64(laptops), resBody["data"].(map[string]interface{})["parent_id"])
+
+	status, resBody = doJSON(router, http.MethodGet, fmt.Sprintf("/api/categories/%d/ancestors", accessories), "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, []string{"Electronics", "Laptops"}, names(resBody["data"]))
+
+	// A name-only update keeps the parent.
+	status, resBody = doJSON(router, http.MethodPut, fmt.Sprintf("/api/categories/%d", accessories), `{"name": "Laptop Accessories"}`)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, float64(laptops), resBody["data"].(map[string]interface{})["parent_id"])
+
+	status, _ = doJSON(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d", laptops), "")
+	assert.Equal(t, 409, status)
+
+	status, resBody = doJSON(router, http.MethodPost, fmt.Sprintf("/api/categories/%d/move", accessories), `{"parent_id": null}`)
+	assert.Equal(t, 200, status)
+	assert.Nil(t, resBody["data"].(map[string]interface{})["parent_id"])
+
+	status, _ = doJSON(router, http.MethodDelete, fmt.Sprintf("/api/categories/%d", laptops), "")
+	assert.Equal(t, 200, status)
+
+	status, _ = doJSON(router, http.MethodGet, "/api/categories/10000/subtree", "")
+	assert.Equal(t, 404, status)
+}
+
+func TestCategoryHierarchy(t *testing.T) {
+	DB := newTestDB()
+	truncateCategory(DB)
+
+	testCategoryHierarchy(t, setUpRouter(DB))
+}
+
+func TestMemoryCategoryHierarchy(t *testing.T) {
+	testCategoryHierarchy(t, setUpMemoryRouter(repository.NewCategoryRepositoryMemory()))
+}
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/controller"
+	"github.com/rtanx/golang-restful-api/exception"
+	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/i18n"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/rtanx/golang-restful-api/model/domain"
+	webrequest "github.com/rtanx/golang-restful-api/model/web/request"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/rtanx/golang-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func setUpMemoryRouter(categoryRepository *repository.CategoryRepositoryMemory) http.Handler {
+	validate := helper.NewValidator()
+	translator, err := i18n.NewTranslator(validate)
+	helper.PanicfIfErr(err)
+	categoryService := service.NewCategoryService(categoryRepository, categoryRepository, validate)
+	categoryController := controller.NewCategoryController(categoryService, config.ConcurrencyConfig{})
+
+	router := app.NewRouter(categoryController, nil)
+
+	return middleware.NewAuthMiddleware(middleware.NewLocaleMiddleware(router, translator), newTestKeyStore(), nil, nil)
+}
+
+func TestMemoryCreateAndListCategory(t *testing.T) {
+	url := fmt.Sprintf("http://%s:%d/api/categories", HOST, PORT)
+
+	router := setUpMemoryRouter(repository.NewCategoryRepositoryMemory())
+
+	for _, name := range []string{"Gadget", "Computer"} {
+		request := httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"name": "`+name+`"}`))
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("X-API-KEY", "RAHASIA")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		assert.Equal(t, 200, recorder.Result().StatusCode)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	resp := recorder.Result()
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resBodyByte, _ := io.ReadAll(resp.Body)
+	var resBody map[string]interface{}
+	json.Unmarshal(resBodyByte, &resBody)
+
+	var categories []interface{} = resBody["data"].([]interface{})
+	assert.Len(t, categories, 2)
+	assert.Equal(t, int64(1), int64(((categories[0].(map[string]interface{}))["id"]).(float64)))
+	assert.Equal(t, "Gadget", ((categories[0].(map[string]interface{}))["name"]))
+	assert.Equal(t, int64(2), int64(((categories[1].(map[string]interface{}))["id"]).(float64)))
+	assert.Equal(t, "Computer", ((categories[1].(map[string]interface{}))["name"]))
+}
+
+func TestMemoryServiceNotFound(t *testing.T) {
+	categoryRepository := repository.NewCategoryRepositoryMemory()
+	categoryService := service.NewCategoryService(categoryRepository, categoryRepository, helper.NewValidator())
+
+	_, err := categoryService.FindById(context.Background(), 1000)
+
+	var notFound exception.NotFoundError
+	assert.True(t, errors.As(err, &notFound))
+	assert.True(t, errors.Is(err, repository.ErrCategoryNotFound))
+
+	_, err = categoryService.Update(context.Background(), webrequest.CategoryUpdateRequest{Id: 1000, Name: "Gadget"})
+	assert.True(t, errors.Is(err, repository.ErrCategoryNotFound))
+}
+
+func TestMemoryRollback(t *testing.T) {
+	categoryRepository := repository.NewCategoryRepositoryMemory()
+	ctx := context.Background()
+
+	tx, _ := categoryRepository.Begin(ctx)
+	c, _ := categoryRepository.Save(ctx, tx, domain.Category{Name: "Gadget"})
+	tx.Commit()
+
+	tx, _ = categoryRepository.Begin(ctx)
+	categoryRepository.Save(ctx, tx, domain.Category{Name: "Computer"})
+	categoryRepository.Delete(ctx, tx, c)
+	tx.Rollback()
+
+	tx, _ = categoryRepository.Begin(ctx)
+	categories, err := categoryRepository.FindAll(ctx, tx, repository.CategoryQuery{})
+	tx.Commit()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.Category{c}, categories)
+}
+
+func newTestKeyStore() auth.KeyStore {
+	keyStore := auth.NewMemoryKeyStore()
+	_, err := auth.EnsureKey(context.Background(), keyStore, "test", "RAHASIA",
+		[]string{auth.ScopeCategoriesRead, auth.ScopeCategoriesWrite, auth.ScopeCategoriesDelete})
+	helper.PanicfIfErr(err)
+	return keyStore
+}
+
+func TestMemoryMalformedRequest(t *testing.T) {
+	router := setUpMemoryRouter(repository.NewCategoryRepositoryMemory())
+	createCategory(t, router, "Gadget", nil)
+
+	for _, body := range []string{`{"name": `, ``, `{"name": 1}`} {
+		status, resBody := doJSON(router, http.MethodPost, "/api/categories", body)
+		assert.Equal(t, 400, status, body)
+		assert.Equal(t, "invalid JSON body", resBody["data"], body)
+
+		status, _ = doJSON(router, http.MethodPut, "/api/categories/1", body)
+		assert.Equal(t, 400, status, body)
+	}
+
+	for _, request := range []struct{ method, path string }{
+		{http.MethodGet, "/api/categories/abc"},
+		{http.MethodPut, "/api/categories/abc"},
+		{http.MethodDelete, "/api/categories/abc"},
+		{http.MethodPost, "/api/categories/abc/move"},
+		{http.MethodGet, "/api/categories/abc/children"},
+		{http.MethodGet, "/api/categories/abc/ancestors"},
+		{http.MethodGet, "/api/categories/abc/subtree"},
+	} {
+		status, resBody := doJSON(router, request.method, request.path, `{"name": "Gizmo"}`)
+		assert.Equal(t, 400, status, request.path)
+		assert.Equal(t, "categoryId must be an integer", resBody["data"], request.path)
+	}
+}
@@ -0,0 +1,226 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/client"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/middleware"
+	webrequest "github.com/rtanx/golang-restful-api/model/web/request"
+	webresponse "github.com/rtanx/golang-restful-api/model/web/response"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/rtanx/golang-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ service.CategoryService = client.NewCategoryClient(nil)
+
+func newTestClient(server *httptest.Server, credentials client.Credentials) client.CategoryClient {
+	c := client.NewClient(server.URL, credentials)
+	c.MinBackoff = time.Millisecond
+	c.MaxBackoff = 10 * time.Millisecond
+	return client.NewCategoryClient(c)
+}
+
+func TestClientCategories(t *testing.T) {
+	server := httptest.NewServer(middleware.RequestID(setUpMemoryRouter(repository.NewCategoryRepositoryMemory())))
+	defer server.Close()
+	categories := newTestClient(server, client.APIKey("RAHASIA"))
+	ctx := context.Background()
+
+	gadget, err := categories.Create(ctx, webrequest.CategoryCreateRequest{Name: "Gadget"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Gadget", gadget.Name)
+	phone, err := categories.Create(ctx, webrequest.CategoryCreateRequest{Name: "Phone", ParentId: &gadget.Id})
+	assert.Nil(t, err)
+	assert.Equal(t, &gadget.Id, phone.ParentId)
+
+	updated, err := categories.Update(ctx, webrequest.CategoryUpdateRequest{Id: gadget.Id, Name: "Gadgets", IfMatch: []int64{gadget.Version}})
+	assert.Nil(t, err)
+	assert.Equal(t, webresponse.CategoryResponse{Id: gadget.Id, Name: "Gadgets", Version: 2}, updated)
+
+	_, err = categories.Update(ctx, webrequest.CategoryUpdateRequest{Id: gadget.Id, Name: "Stale", IfMatch: []int64{gadget.Version}})
+	var preconditionFailed client.PreconditionFailedError
+	assert.True(t, errors.As(err, &preconditionFailed))
+
+	found, err := categories.FindById(ctx, gadget.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, updated, found)
+
+	ancestors, err := categories.FindAncestors(ctx, phone.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, []webresponse.CategoryResponse{updated}, ancestors)
+
+	tree, err := categories.FindSubtree(ctx, gadget.Id)
+	assert.Nil(t, err)
+	assert.Len(t, tree.Children, 1)
+
+	moved, err := categories.Move(ctx, webrequest.CategoryMoveRequest{Id: phone.Id})
+	assert.Nil(t, err)
+	assert.Nil(t, moved.ParentId)
+
+	assert.Nil(t, categories.Delete(ctx, webrequest.CategoryDeleteRequest{Id: phone.Id, IfMatch: []int64{moved.Version}}))
+	_, err = categories.FindById(ctx, phone.Id)
+	var notFound client.NotFoundError
+	assert.True(t, errors.As(err, &notFound))
+	assert.Equal(t, http.StatusNotFound, notFound.StatusCode)
+	assert.NotEmpty(t, notFound.RequestID)
+}
+
+func TestClientPaging(t *testing.T) {
+	server := httptest.NewServer(setUpMemoryRouter(repository.NewCategoryRepositoryMemory()))
+	defer server.Close()
+	categories := newTestClient(server, client.APIKey("RAHASIA"))
+	ctx := context.Background()
+
+	names := []string{"A", "B", "C", "D", "E"}
+	for _, name := range names {
+		_, err := categories.Create(ctx, webrequest.CategoryCreateRequest{Name: name})
+		assert.Nil(t, err)
+	}
+
+	page, err := categories.FindAll(ctx, webrequest.CategoryListRequest{Page: 2, Size: 2, Sort: "name"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Categories, 2)
+	assert.Equal(t, "C", page.Categories[0].Name)
+	meta := page.Meta.(webresponse.PageMeta)
+	assert.Equal(t, 2, meta.Page)
+	assert.Equal(t, int64(5), meta.TotalItems)
+	assert.Equal(t, int64(3), meta.TotalPages)
+
+	next, err := categories.FindAll(ctx, webrequest.CategoryListRequest{Size: 2, Sort: "name", Cursor: meta.NextCursor})
+	assert.Nil(t, err)
+	assert.Equal(t, "E", next.Categories[0].Name)
+	assert.Equal(t, webresponse.CursorMeta{Size: 2}, next.Meta)
+
+	var listed []string
+	err = categories.ForEach(ctx, webrequest.CategoryListRequest{Size: 2, Sort: "name"}, func(category webresponse.CategoryResponse) error {
+		listed = append(listed, category.Name)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, names, listed)
+}
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(setUpMemoryRouter(repository.NewCategoryRepositoryMemory()))
+	defer server.Close()
+	categories := newTestClient(server, client.APIKey("RAHASIA"))
+	ctx := context.Background()
+
+	_, err := categories.Create(ctx, webrequest.CategoryCreateRequest{})
+	var badRequest client.BadRequestError
+	assert.True(t, errors.As(err, &badRequest))
+	assert.Equal(t, map[string]string{"name": "name is a required field"}, badRequest.Fields)
+
+	_, err = categories.Create(ctx, webrequest.CategoryCreateRequest{Name: "Gadget"})
+	assert.Nil(t, err)
+	_, err = categories.Create(ctx, webrequest.CategoryCreateRequest{Name: "Gadget"})
+	var conflict client.ConflictError
+	assert.True(t, errors.As(err, &conflict))
+
+	_, err = newTestClient(server, client.APIKey("WRONG")).FindById(ctx, 1)
+	var unauthorized client.UnauthorizedError
+	assert.True(t, errors.As(err, &unauthorized))
+	var apiErr *client.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+func TestClientHMAC(t *testing.T) {
+	signatures := auth.NewHMACVerifier(config.HMACConfig{
+		Keys:    []config.HMACKey{{Id: "batch", Secret: "batch-secret", Scopes: []string{auth.ScopeCategoriesWrite}}},
+		MaxSkew: config.Duration(5 * time.Minute),
+	})
+	handler := middleware.NewAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":200,"status":"OK","data":{"id":1,"name":"Gadget"}}`))
+	}), newTestKeyStore(), nil, signatures)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	category, err := newTestClient(server, client.HMACKey{Id: "batch", Secret: "batch-secret"}).
+		Create(context.Background(), webrequest.CategoryCreateRequest{Name: "Gadget"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Gadget", category.Name)
+
+	_, err = newTestClient(server, client.HMACKey{Id: "batch", Secret: "wrong"}).
+		Create(context.Background(), webrequest.CategoryCreateRequest{Name: "Gadget"})
+	assert.True(t, errors.As(err, &client.UnauthorizedError{}))
+}
+
+func TestClientRetries(t *testing.T) {
+	var calls int32
+	failures := int32(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= atomic.LoadInt32(&failures) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"code":200,"status":"OK","data":{"id":1,"name":"Gadget"}}`))
+	}))
+	defer server.Close()
+	categories := newTestClient(server, nil)
+	ctx := context.Background()
+
+	category, err := categories.FindById(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Gadget", category.Name)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// POST is not idempotent, so it is not retried.
+	atomic.StoreInt32(&calls, 0)
+	_, err = categories.Create(ctx, webrequest.CategoryCreateRequest{Name: "Gadget"})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Retries stop after MaxRetries.
+	atomic.StoreInt32(&calls, 0)
+	atomic.StoreInt32(&failures, 10)
+	_, err = categories.FindById(ctx, 1)
+	var apiErr *client.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+
+	// A cancelled context stops the retries.
+	atomic.StoreInt32(&calls, 0)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = categories.FindById(cancelled, 1)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestClientRetryAfter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"code":200,"status":"OK","data":null}`))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	err := newTestClient(server, nil).Delete(context.Background(), webrequest.CategoryDeleteRequest{Id: 1})
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Second))
+
+	// Without retries the 429 is returned with its Retry-After.
+	atomic.StoreInt32(&calls, 0)
+	c := client.NewClient(server.URL, nil)
+	c.MaxRetries = 0
+	err = client.NewCategoryClient(c).Delete(context.Background(), webrequest.CategoryDeleteRequest{Id: 1})
+	var tooManyRequests client.TooManyRequestsError
+	assert.True(t, errors.As(err, &tooManyRequests))
+	assert.Equal(t, time.Second, tooManyRequests.RetryAfter)
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func lookupEnvFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+server:
+  host: 0.0.0.0
+  port: 3000
+database:
+  dialect: sqlite
+  dsn: file:app.db
+  conn_max_lifetime: 5m
+auth:
+  api_key: from-file
+`), 0600)
+	assert.Nil(t, err)
+
+	env := map[string]string{
+		"CONFIG_FILE":  file,
+		"SERVER_PORT":  "4000",
+		"AUTH_API_KEY": "from-env",
+	}
+	cfg, args, err := config.Load([]string{"-api-key", "from-flag", "migrate", "status"}, lookupEnvFrom(env))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "0.0.0.0", cfg.Server.Host)
+	assert.Equal(t, 4000, cfg.Server.Port)
+	assert.Equal(t, "sqlite", cfg.Database.Dialect)
+	assert.Equal(t, config.Duration(5*time.Minute), cfg.Database.ConnMaxLifetime)
+	assert.Equal(t, 20, cfg.Database.MaxOpenConns)
+	assert.True(t, cfg.Concurrency.RequireIfMatch)
+	assert.Equal(t, "from-flag", cfg.Auth.APIKey)
+	assert.Equal(t, []string{"migrate", "status"}, args)
+}
+
+func TestConfigJSONFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(file, []byte(`{"database": {"dialect": "postgres", "dsn": "postgres://localhost/app", "conn_max_idle_time": "90s"}, "auth": {"api_key": "secret"}}`), 0600)
+	assert.Nil(t, err)
+
+	cfg, _, err := config.Load([]string{"-config", file}, lookupEnvFrom(nil))
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres", cfg.Database.Dialect)
+	assert.Equal(t, config.Duration(90*time.Second), cfg.Database.ConnMaxIdleTime)
+}
+
+func TestConfigValidation(t *testing.T) {
+	_, _, err := config.Load(nil, lookupEnvFrom(map[string]string{"AUTH_KEY_STORE": "memory"}))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "auth.api_key must be set")
+
+	_, _, err = config.Load(nil, lookupEnvFrom(map[string]string{"AUTH_KEY_STORE": "vault"}))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "auth.key_store")
+
+	env := map[string]string{"AUTH_API_KEY": "secret", "DB_DIALECT": "oracle", "SERVER_PORT": "70000"}
+	_, _, err = config.Load(nil, lookupEnvFrom(env))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "server.port")
+	assert.Contains(t, err.Error(), "oracle")
+
+	env = map[string]string{"AUTH_API_KEY": "secret", "DB_MAX_OPEN_CONNS": "many"}
+	_, _, err = config.Load(nil, lookupEnvFrom(env))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "DB_MAX_OPEN_CONNS")
+
+	env = map[string]string{"AUTH_API_KEY": "secret", "OPENAPI_VALIDATE_REQUESTS": "true", "OPENAPI_MAX_BODY_BYTES": "0"}
+	_, _, err = config.Load(nil, lookupEnvFrom(env))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "openapi.max_body_bytes must be positive")
+}
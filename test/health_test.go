@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/db/migration"
+	"github.com/rtanx/golang-restful-api/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func getHealth(handler http.Handler, path string) (int, map[string]interface{}) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var resBody map[string]interface{}
+	json.Unmarshal(recorder.Body.Bytes(), &resBody)
+	return recorder.Code, resBody["data"].(map[string]interface{})
+}
+
+func TestReadiness(t *testing.T) {
+	DB := newTestDB()
+	defer DB.Close()
+	migrator, err := migration.NewMigrator(DB, testDialect)
+	assert.Nil(t, err)
+
+	checker := health.NewChecker(100 * time.Millisecond)
+	checker.Register("database", health.DatabaseCheck(DB))
+	checker.Register("migrations", health.MigrationsCheck(migrator))
+
+	status, data := getHealth(checker.LivenessHandler(), "/healthz")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "UP", data["status"])
+
+	status, data = getHealth(checker.ReadinessHandler(), "/readyz")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "UP", data["status"])
+	checks := data["checks"].(map[string]interface{})
+	assert.Len(t, checks, 3)
+	for _, name := range []string{"database", "migrations", "shutdown"} {
+		assert.Equal(t, "UP", checks[name].(map[string]interface{})["status"], name)
+	}
+
+	// A registered check that hangs is cut off by the timeout.
+	checker.Register("cache", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	status, data = getHealth(checker.ReadinessHandler(), "/readyz")
+	assert.Equal(t, 503, status)
+	assert.Equal(t, "DOWN", data["status"])
+	cache := data["checks"].(map[string]interface{})["cache"].(map[string]interface{})
+	assert.Equal(t, "DOWN", cache["status"])
+	assert.Equal(t, context.DeadlineExceeded.Error(), cache["error"])
+}
+
+func TestReadinessShutdownAndPending(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Register("queue", func(ctx context.Context) error {
+		return errors.New("queue unreachable")
+	})
+
+	migrator := &migration.Migrator{DB: newTestDB(), Dialect: testDialect, Migrations: []migration.Migration{{Version: 999999, Name: "future"}}}
+	defer migrator.DB.Close()
+	checker.Register("migrations", health.MigrationsCheck(migrator))
+	checker.ShuttingDown()
+
+	status, data := getHealth(checker.ReadinessHandler(), "/readyz")
+	assert.Equal(t, 503, status)
+	checks := data["checks"].(map[string]interface{})
+	assert.Equal(t, "queue unreachable", checks["queue"].(map[string]interface{})["error"])
+	assert.Equal(t, "1 pending migrations", checks["migrations"].(map[string]interface{})["error"])
+	assert.Equal(t, "shutting down", checks["shutdown"].(map[string]interface{})["error"])
+
+	// Liveness is unaffected.
+	status, _ = getHealth(checker.LivenessHandler(), "/healthz")
+	assert.Equal(t, 200, status)
+}
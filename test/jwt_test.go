@@ -0,0 +1,178 @@
+package test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func signJWT(t *testing.T, alg string, kid string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		assert.Nil(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		assert.Nil(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+	}})
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(file, jwks, 0600))
+	return file
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	otherEcKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	secret := []byte("jwt-secret")
+
+	verifier, err := auth.NewJWTVerifier(config.JWTConfig{
+		Secret:   string(secret),
+		JWKSFile: writeJWKS(t, rsaKey, ecKey),
+		Issuer:   "https://issuer.example.com",
+		Audience: "categories",
+		Leeway:   config.Duration(30 * time.Second),
+	})
+	assert.Nil(t, err)
+
+	now := time.Now().UTC()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss": "https://issuer.example.com",
+			"aud": []string{"categories", "other"},
+			"sub": "user-1",
+			"exp": now.Add(time.Hour).Unix(),
+			"nbf": now.Add(-time.Minute).Unix(),
+		}
+		for name, value := range overrides {
+			c[name] = value
+		}
+		return c
+	}
+
+	valid := map[string]string{
+		"HS256": signJWT(t, "HS256", "", secret, claims(nil)),
+		"RS256": signJWT(t, "RS256", "rsa-1", rsaKey, claims(nil)),
+		"ES256": signJWT(t, "ES256", "ec-1", ecKey, claims(map[string]interface{}{"aud": "categories"})),
+	}
+	for alg, token := range valid {
+		verified, err := verifier.Verify(token, now)
+		assert.Nil(t, err, alg)
+		assert.Equal(t, "user-1", verified.Subject, alg)
+		assert.Contains(t, verified.Audience, "categories", alg)
+	}
+
+	invalid := map[string]string{
+		"expired":       signJWT(t, "HS256", "", secret, claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})),
+		"not yet valid": signJWT(t, "HS256", "", secret, claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})),
+		"issuer":        signJWT(t, "HS256", "", secret, claims(map[string]interface{}{"iss": "https://evil.example.com"})),
+		"audience":      signJWT(t, "HS256", "", secret, claims(map[string]interface{}{"aud": "billing"})),
+		"wrong key":     signJWT(t, "ES256", "ec-1", otherEcKey, claims(nil)),
+		"wrong secret":  signJWT(t, "HS256", "", []byte("guess"), claims(nil)),
+		"wrong kid":     signJWT(t, "RS256", "rsa-2", rsaKey, claims(nil)),
+		"alg none":      signJWT(t, "none", "", nil, claims(nil)),
+		"malformed":     "not.a-token",
+	}
+	for reason, token := range invalid {
+		_, err := verifier.Verify(token, now)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken, reason)
+	}
+
+	// The leeway tolerates a token that expired moments ago.
+	_, err = verifier.Verify(signJWT(t, "HS256", "", secret, claims(map[string]interface{}{"exp": now.Add(-10 * time.Second).Unix()})), now)
+	assert.Nil(t, err)
+
+	// Fractional timestamps keep their nanoseconds.
+	verified, err := verifier.Verify(signJWT(t, "HS256", "", secret, claims(map[string]interface{}{"exp": 4e9 + 0.5})), now)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(4e9, 5e8).UTC(), *verified.ExpiresAt)
+
+	// Timestamps too large to convert are rejected rather than wrapped
+	// around into the past, which would make a token valid early.
+	for _, timestamp := range []map[string]interface{}{{"nbf": 1e19}, {"exp": 1e300}, {"iat": -1e19}} {
+		_, err = verifier.Verify(signJWT(t, "HS256", "", secret, claims(timestamp)), now)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken, timestamp)
+	}
+}
+
+func TestAuthMiddlewareBearer(t *testing.T) {
+	secret := []byte("jwt-secret")
+	verifier, err := auth.NewJWTVerifier(config.JWTConfig{Secret: string(secret)})
+	assert.Nil(t, err)
+
+	handler := middleware.NewAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.ClaimsFromContext(r.Context())
+		assert.True(t, ok)
+		fmt.Fprint(w, claims.Subject)
+	}), newTestKeyStore(), verifier, nil)
+
+	token := signJWT(t, "HS256", "", secret, map[string]interface{}{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+
+	request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "user-1", recorder.Body.String())
+
+	request = httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	request.Header.Add("Authorization", "Bearer "+token+"x")
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, 401, recorder.Code)
+	assert.Equal(t, "Bearer", recorder.Header().Get("WWW-Authenticate"))
+
+	// With only the API key method enabled, bearer tokens are not accepted.
+	apiKeyOnly := middleware.NewAuthMiddleware(http.NotFoundHandler(), newTestKeyStore(), nil, nil)
+	request = httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	request.Header.Add("Authorization", "Bearer "+token)
+	recorder = httptest.NewRecorder()
+	apiKeyOnly.ServeHTTP(recorder, request)
+	assert.Equal(t, 401, recorder.Code)
+}
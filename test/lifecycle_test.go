@@ -0,0 +1,160 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycleOrder(t *testing.T) {
+	var events []string
+	hook := func(name string, startErr error) app.Hook {
+		return app.Hook{
+			Name: name,
+			OnStart: func(ctx context.Context) error {
+				events = append(events, "start "+name)
+				return startErr
+			},
+			OnStop: func(ctx context.Context) error {
+				events = append(events, "stop "+name)
+				return nil
+			},
+		}
+	}
+
+	lifecycle := app.NewLifecycle()
+	lifecycle.Append(hook("db", nil))
+	lifecycle.Append(hook("cache", nil))
+	assert.Nil(t, lifecycle.Start(context.Background()))
+	assert.Nil(t, lifecycle.Stop(context.Background()))
+	assert.Equal(t, []string{"start db", "start cache", "stop cache", "stop db"}, events)
+
+	// A failed start stops only the hooks that started.
+	events = nil
+	lifecycle = app.NewLifecycle()
+	lifecycle.Append(hook("db", nil))
+	lifecycle.Append(hook("server", errors.New("address in use")))
+	lifecycle.Append(hook("worker", nil))
+	err := lifecycle.Start(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "start server: address in use")
+	assert.Equal(t, []string{"start db", "start server", "stop db"}, events)
+}
+
+func TestLifecycleRunStopsOnFailure(t *testing.T) {
+	lifecycle := app.NewLifecycle()
+	stopped := false
+	lifecycle.Append(app.Hook{
+		Name: "worker",
+		OnStart: func(ctx context.Context) error {
+			go lifecycle.Fail(errors.New("worker crashed"))
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopped = true
+			return nil
+		},
+	})
+
+	err := lifecycle.Run(context.Background(), time.Second)
+	assert.NotNil(t, err)
+	assert.Equal(t, "worker crashed", err.Error())
+	assert.True(t, stopped)
+}
+
+func TestServerGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := app.NewServer(config.ServerConfig{Host: "127.0.0.1", Port: 0}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("drained"))
+	}))
+	assert.Equal(t, "127.0.0.1:0", server.Addr)
+
+	lifecycle := app.NewLifecycle()
+	lifecycle.Append(app.ServerHook(server, lifecycle))
+	assert.Nil(t, lifecycle.Start(context.Background()))
+	assert.NotEqual(t, "127.0.0.1:0", server.Addr, "the picked port is recorded")
+
+	body := make(chan string)
+	go func() {
+		resp, err := http.Get("http://" + server.Addr)
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		content, _ := io.ReadAll(resp.Body)
+		body <- string(content)
+	}()
+	<-started
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- lifecycle.Stop(context.Background())
+	}()
+
+	// Shutdown waits for the request in flight.
+	select {
+	case <-stopped:
+		t.Fatal("stopped before the request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	assert.Equal(t, "drained", <-body)
+	assert.Nil(t, <-stopped)
+
+	_, err := http.Get("http://" + server.Addr)
+	assert.NotNil(t, err, "no longer accepting connections")
+}
+
+func TestReadinessHookDelaysShutdown(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	server := app.NewServer(config.ServerConfig{Host: "127.0.0.1", Port: 0}, checker.ReadinessHandler())
+
+	lifecycle := app.NewLifecycle()
+	lifecycle.Append(app.ServerHook(server, lifecycle))
+	lifecycle.Append(app.ReadinessHook(checker, 200*time.Millisecond))
+	assert.Nil(t, lifecycle.Start(context.Background()))
+
+	status := func() int {
+		resp, err := http.Get("http://" + server.Addr)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, 200, status())
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- lifecycle.Stop(context.Background())
+	}()
+
+	// During the delay the server still answers, reporting not ready.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, status())
+	assert.Nil(t, <-stopped)
+	assert.Equal(t, 0, status(), "no longer accepting connections")
+
+	// The delay ends early when the stop context does.
+	lifecycle = app.NewLifecycle()
+	lifecycle.Append(app.ReadinessHook(health.NewChecker(time.Second), time.Hour))
+	assert.Nil(t, lifecycle.Start(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := lifecycle.Stop(ctx)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "stop readiness: context deadline exceeded")
+}
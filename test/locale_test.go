@@ -0,0 +1,50 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalizedValidationErrors(t *testing.T) {
+	url := fmt.Sprintf("http://%s:%d/api/categories", HOST, PORT)
+	router := setUpMemoryRouter(repository.NewCategoryRepositoryMemory())
+
+	cases := []struct {
+		acceptLanguage string
+		locale         string
+		message        string
+	}{
+		{"", "en", "name is a required field"},
+		{"en-US,en;q=0.9", "en", "name is a required field"},
+		{"id-ID,id;q=0.9,en;q=0.8", "id", "name wajib diisi"},
+		{"fr-FR, id;q=0.5", "id", "name wajib diisi"},
+		{"fr-FR", "en", "name is a required field"},
+	}
+	for _, c := range cases {
+		request := httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"name": ""}`))
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("Accept-Language", c.acceptLanguage)
+		request.Header.Add("X-API-KEY", "RAHASIA")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		resp := recorder.Result()
+		assert.Equal(t, 400, resp.StatusCode, c.acceptLanguage)
+		assert.Equal(t, c.locale, resp.Header.Get("Content-Language"), c.acceptLanguage)
+
+		resBodyByte, _ := io.ReadAll(resp.Body)
+		var resBody map[string]interface{}
+		json.Unmarshal(resBodyByte, &resBody)
+
+		assert.Equal(t, map[string]interface{}{"name": c.message}, resBody["data"], c.acceptLanguage)
+	}
+}
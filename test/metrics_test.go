@@ -0,0 +1,105 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/controller"
+	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/i18n"
+	"github.com/rtanx/golang-restful-api/metrics"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/rtanx/golang-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.NewHTTPMetrics(registry).Errors.WithLabelValues(`say "hi"`).Inc()
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler(registry).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Result().Body)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	assert.Contains(t, string(body), "# TYPE http_errors_total counter\n")
+	assert.Contains(t, string(body), `http_errors_total{type="say \"hi\""} 1`)
+	assert.Contains(t, string(body), "# TYPE go_goroutines gauge\n")
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	registry := metrics.NewRegistry()
+	httpMetrics := metrics.NewHTTPMetrics(registry)
+
+	categoryRepository := repository.NewCategoryRepositoryMemory()
+	validate := helper.NewValidator()
+	translator, err := i18n.NewTranslator(validate)
+	helper.PanicfIfErr(err)
+	categoryService := service.NewCategoryService(categoryRepository, categoryRepository, validate)
+	router := app.NewRouter(controller.NewCategoryController(categoryService, config.ConcurrencyConfig{}), nil)
+	handler := middleware.NewChain(
+		middleware.Metrics(httpMetrics),
+		middleware.Recovery,
+		middleware.Auth(newTestKeyStore(), nil, nil),
+		middleware.Locale(translator),
+	).Then(router)
+
+	id := createCategory(t, handler, "Gadget", nil)
+	status, _ := doJSON(handler, http.MethodGet, "/api/categories/1000", "")
+	assert.Equal(t, 404, status)
+	status, _ = doJSON(handler, http.MethodGet, "/nowhere", "")
+	assert.Equal(t, 404, status)
+	status, _ = doJSON(handler, http.MethodGet, fmt.Sprintf("/api/categories/%d", id), "")
+	assert.Equal(t, 200, status)
+
+	assert.Equal(t, float64(1), requestCount(httpMetrics, "POST", "/api/categories", "200"))
+	assert.Equal(t, float64(1), requestCount(httpMetrics, "GET", "/api/categories/:categoryId", "200"))
+	assert.Equal(t, float64(1), requestCount(httpMetrics, "GET", "/api/categories/:categoryId", "404"))
+	assert.Equal(t, float64(1), requestCount(httpMetrics, "GET", metrics.UnmatchedRoute, "404"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpMetrics.Errors.WithLabelValues("not_found")))
+
+	// Requests rejected before routing are counted as unmatched.
+	request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, 401, recorder.Code)
+	assert.Equal(t, float64(1), requestCount(httpMetrics, "GET", metrics.UnmatchedRoute, "401"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(httpMetrics.Errors.WithLabelValues("unauthorized")))
+
+	recorder = httptest.NewRecorder()
+	metrics.Handler(registry).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Result().Body)
+	assert.Contains(t, string(body), `http_requests_total{method="GET",route="/api/categories/:categoryId",status="404"} 1`)
+	assert.Contains(t, string(body), `http_request_duration_seconds_count{method="POST",route="/api/categories"} 1`)
+	assert.Contains(t, string(body), `http_errors_total{type="not_found"} 1`)
+}
+
+func TestMetricsDBStats(t *testing.T) {
+	DB := newTestDB()
+	defer DB.Close()
+	registry := prometheus.NewRegistry()
+	metrics.RegisterDBStats(registry, DB)
+
+	err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP go_sql_max_open_connections Maximum number of open connections to the database.
+# TYPE go_sql_max_open_connections gauge
+go_sql_max_open_connections{db_name="default"} 20
+`), "go_sql_max_open_connections")
+	assert.NoError(t, err)
+	count, err := testutil.GatherAndCount(registry, "go_sql_open_connections", "go_sql_wait_count_total")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func requestCount(httpMetrics *metrics.HTTPMetrics, method string, route string, status string) float64 {
+	return testutil.ToFloat64(httpMetrics.Requests.WithLabelValues(method, route, status))
+}
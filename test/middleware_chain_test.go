@@ -0,0 +1,94 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) middleware.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	base := middleware.NewChain(mark("a"), mark("b"))
+	extended := base.Append(mark("c"))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+
+	extended.Then(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"a", "b", "c", "handler"}, order)
+
+	order = nil
+	base.Then(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"a", "b", "handler"}, order, "Append leaves the original chain alone")
+}
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	var logs bytes.Buffer
+	handler := middleware.NewChain(
+		middleware.RequestID,
+		middleware.AccessLog(log.New(&logs, "", 0)),
+		middleware.Recovery,
+		middleware.Auth(newTestKeyStore(), nil, nil),
+	).Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Request-ID", middleware.RequestIDFromContext(r.Context()))
+		w.Write([]byte("hello"))
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	request.Header.Add("X-Request-ID", "upstream-42")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, "upstream-42", recorder.Header().Get("X-Request-ID"))
+	assert.Equal(t, "upstream-42", recorder.Header().Get("X-Seen-Request-ID"))
+	assert.True(t, strings.HasPrefix(logs.String(), `method=GET path="/api/categories" status=200 latency=`), logs.String())
+	assert.Contains(t, logs.String(), "bytes=5 request_id=upstream-42 credential=key:1\n")
+
+	logs.Reset()
+	request = httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	request.Header.Add("X-Request-ID", "bad id\nwith newline")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	generated := recorder.Header().Get("X-Request-ID")
+	assert.Len(t, generated, 32)
+	assert.Contains(t, logs.String(), "status=401")
+	assert.Contains(t, logs.String(), "request_id="+generated+" credential=-")
+}
+
+func TestRecoveryOutsideRouter(t *testing.T) {
+	var logs bytes.Buffer
+	handler := middleware.NewChain(
+		middleware.RequestID,
+		middleware.AccessLog(log.New(&logs, "", 0)),
+		middleware.Recovery,
+	).Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/categories", nil))
+
+	assert.Equal(t, 500, recorder.Code)
+	var resBody map[string]interface{}
+	json.Unmarshal(recorder.Body.Bytes(), &resBody)
+	assert.Equal(t, "Internal Server Error", resBody["status"])
+	assert.Contains(t, logs.String(), "status=500")
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rtanx/golang-restful-api/db"
+	"github.com/rtanx/golang-restful-api/db/migration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationUpDownRedoStatus(t *testing.T) {
+	DB, err := sql.Open(db.SQLite.DriverName(), "file:"+filepath.Join(t.TempDir(), "migration.db"))
+	assert.Nil(t, err)
+	defer DB.Close()
+
+	migrator, err := migration.NewMigrator(DB, db.SQLite)
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	pending, err := migrator.Pending(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, len(migrator.Migrations), pending)
+
+	applied, err := migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, len(migrator.Migrations), len(applied))
+
+	applied, err = migrator.Up(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.False(t, status.AppliedAt.IsZero())
+	}
+
+	latest := migrator.Migrations[len(migrator.Migrations)-1]
+	redone, err := migrator.Redo(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, latest.Version, redone.Version)
+
+	for range migrator.Migrations {
+		_, err = migrator.Down(ctx)
+		assert.Nil(t, err)
+	}
+	_, err = migrator.Down(ctx)
+	assert.Equal(t, migration.ErrNoMigration, err)
+
+	_, err = DB.Exec("SELECT id FROM category")
+	assert.NotNil(t, err)
+}
+
+// TestMigrationExistingSchema starts from a database set up by hand with the
+// schema that predates migrations.
+func TestMigrationExistingSchema(t *testing.T) {
+	DB, err := sql.Open(db.SQLite.DriverName(), "file:"+filepath.Join(t.TempDir(), "existing.db"))
+	assert.Nil(t, err)
+	defer DB.Close()
+	_, err = DB.Exec("CREATE TABLE category(id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(200) NOT NULL)")
+	assert.Nil(t, err)
+	_, err = DB.Exec("INSERT INTO category(name) VALUES ('Gadget')")
+	assert.Nil(t, err)
+
+	migrator, err := migration.NewMigrator(DB, db.SQLite)
+	assert.Nil(t, err)
+	applied, err := migrator.Up(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, len(migrator.Migrations), len(applied))
+
+	var name string
+	var version int64
+	err = DB.QueryRow("SELECT name, version FROM category WHERE id = 1").Scan(&name, &version)
+	assert.Nil(t, err)
+	assert.Equal(t, "Gadget", name)
+	assert.Equal(t, int64(1), version)
+}
+
+func TestMigrationLoad(t *testing.T) {
+	migrations, err := migration.Load(fstest.MapFS{
+		"000002_second.up.sql":  {Data: []byte("CREATE TABLE b(id INTEGER);")},
+		"000001_first.up.sql":   {Data: []byte("CREATE TABLE a(id INTEGER);")},
+		"000001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+		"README.md":             {Data: []byte("ignored")},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE a;", migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+
+	_, err = migration.Load(fstest.MapFS{
+		"first.up.sql": {Data: []byte("CREATE TABLE a(id INTEGER);")},
+	})
+	assert.NotNil(t, err)
+}
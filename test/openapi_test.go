@@ -0,0 +1,254 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/controller"
+	"github.com/rtanx/golang-restful-api/exception"
+	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/i18n"
+	"github.com/rtanx/golang-restful-api/middleware"
+	webrequest "github.com/rtanx/golang-restful-api/model/web/request"
+	"github.com/rtanx/golang-restful-api/openapi"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/rtanx/golang-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSpec(t *testing.T) *openapi.Document {
+	doc, err := openapi.Load(openapi.Spec)
+	assert.Nil(t, err)
+	return doc
+}
+
+func setUpValidatedRouter(t *testing.T, logs *bytes.Buffer, handler http.Handler) http.Handler {
+	openAPIConfig := config.Default().OpenAPI
+	openAPIConfig.ValidateRequests = true
+	openAPIConfig.ValidateResponses = true
+	return middleware.NewChain(
+		middleware.Recovery,
+		middleware.Auth(newTestKeyStore(), nil, nil),
+		middleware.OpenAPIValidation(loadSpec(t), openAPIConfig, log.New(logs, "", 0)),
+	).Then(handler)
+}
+
+func TestOpenAPIDocs(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	openapi.SpecHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var spec map[string]interface{}
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &spec))
+	assert.Equal(t, "/api", spec["servers"].([]interface{})[0].(map[string]interface{})["url"])
+
+	recorder = httptest.NewRecorder()
+	openapi.DocsHandler("/openapi.json", "/static/swagger-ui/").ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, recorder.Body.String(), `<script src="/static/swagger-ui/swagger-ui-bundle.js"`)
+	assert.Contains(t, recorder.Body.String(), `href="/static/swagger-ui/swagger-ui.css"`)
+	assert.Contains(t, recorder.Body.String(), `"/openapi.json"`)
+}
+
+// TestOpenAPISpecUpToDate fails when app.Routes or the request and response
+// types change without regenerating the committed document.
+func TestOpenAPISpecUpToDate(t *testing.T) {
+	spec, err := openapi.Marshal(app.NewOpenAPI())
+	assert.Nil(t, err)
+	if !bytes.Equal(openapi.Spec, spec) {
+		t.Error("openapi/apispec.json is out of date, run go generate ./openapi")
+	}
+}
+
+func TestOpenAPIGenerator(t *testing.T) {
+	type Item struct {
+		Code   string   `json:"code" validate:"required,min=2,max=8"`
+		Kind   string   `json:"kind,omitempty" validate:"omitempty,oneof=a b"`
+		Count  *int64   `json:"count" validate:"omitempty,min=1,max=10"`
+		Tags   []string `json:"tags,omitempty"`
+		Parent *Item    `json:"parent,omitempty"`
+		secret string
+		Skip   string `json:"-"`
+	}
+	g := openapi.NewGenerator(openapi.Info{Title: "Test", Version: "1"}, "/api")
+
+	assert.Equal(t, "#/components/schemas/Item", g.RequestSchema(Item{}).Ref)
+	item := g.Document.Components.Schemas["Item"]
+	assert.Equal(t, []string{"code"}, item.Required)
+	assert.Equal(t, []string{"code", "count", "kind", "parent", "tags"}, sortedSchemaKeys(item.Properties))
+	assert.Equal(t, "string", item.Properties["code"].Type)
+	assert.Equal(t, 2, *item.Properties["code"].MinLength)
+	assert.Equal(t, 8, *item.Properties["code"].MaxLength)
+	assert.Equal(t, []string{"a", "b"}, item.Properties["kind"].Enum)
+	assert.Equal(t, "integer", item.Properties["count"].Type)
+	assert.True(t, item.Properties["count"].Nullable)
+	assert.Equal(t, 10.0, *item.Properties["count"].Maximum)
+	assert.Equal(t, "array", item.Properties["tags"].Type)
+	assert.Equal(t, "#/components/schemas/Item", item.Properties["parent"].Ref)
+
+	response := g.InlineResponseSchema(Item{})
+	assert.Equal(t, []string{"code", "count"}, response.Required)
+
+	parameters := g.QueryParameters(webrequest.CategoryListRequest{})
+	assert.Equal(t, "page", parameters[0].Name)
+	assert.Equal(t, "query", parameters[0].In)
+	assert.Equal(t, "Page number, starting at 1", parameters[0].Description)
+	assert.Equal(t, 1.0, *parameters[0].Schema.Minimum)
+}
+
+func sortedSchemaKeys(m map[string]*openapi.Schema) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestOpenAPIFindOperation(t *testing.T) {
+	doc := loadSpec(t)
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/api/categories"},
+		{http.MethodPost, "/api/categories"},
+		{http.MethodGet, "/api/categories/1"},
+		{http.MethodPut, "/api/categories/1"},
+		{http.MethodDelete, "/api/categories/1"},
+		{http.MethodPost, "/api/categories/1/move"},
+		{http.MethodGet, "/api/categories/1/children"},
+		{http.MethodGet, "/api/categories/1/ancestors"},
+		{http.MethodGet, "/api/categories/1/subtree"},
+	} {
+		_, _, ok := doc.FindOperation(route.method, route.path)
+		assert.True(t, ok, route)
+	}
+
+	_, params, _ := doc.FindOperation(http.MethodGet, "/api/categories/42/subtree")
+	assert.Equal(t, map[string]string{"categoryId": "42"}, params)
+	_, _, ok := doc.FindOperation(http.MethodPatch, "/api/categories/1")
+	assert.False(t, ok)
+	_, _, ok = doc.FindOperation(http.MethodGet, "/categories")
+	assert.False(t, ok)
+}
+
+func TestOpenAPIRequestValidation(t *testing.T) {
+	var logs bytes.Buffer
+	validate := helper.NewValidator()
+	translator, err := i18n.NewTranslator(validate)
+	helper.PanicfIfErr(err)
+	categoryRepository := repository.NewCategoryRepositoryMemory()
+	categoryService := service.NewCategoryService(categoryRepository, categoryRepository, validate)
+	router := middleware.Locale(translator)(app.NewRouter(controller.NewCategoryController(categoryService, config.ConcurrencyConfig{}), nil))
+	handler := setUpValidatedRouter(t, &logs, router)
+
+	status, resBody := doJSON(handler, http.MethodPost, "/api/categories", `{"name": "`+strings.Repeat("a", 201)+`", "parent_id": "1"}`)
+	assert.Equal(t, 400, status)
+	assert.Equal(t, map[string]interface{}{
+		"name":      "must be at most 200 characters long",
+		"parent_id": "must be an integer",
+	}, resBody["data"])
+
+	status, resBody = doJSON(handler, http.MethodPost, "/api/categories", `{}`)
+	assert.Equal(t, 400, status)
+	assert.Equal(t, map[string]interface{}{"name": "is required"}, resBody["data"])
+
+	status, _ = doJSON(handler, http.MethodPost, "/api/categories", `{"name": `)
+	assert.Equal(t, 400, status)
+
+	status, resBody = doJSON(handler, http.MethodGet, "/api/categories?size=1000&name_match=exact&page=two", "")
+	assert.Equal(t, 400, status)
+	assert.Equal(t, map[string]interface{}{
+		"size":       "must be at most 100",
+		"name_match": "must be one of prefix, contains",
+		"page":       "must be an integer",
+	}, resBody["data"])
+
+	status, _ = doJSON(handler, http.MethodGet, "/api/categories/abc", "")
+	assert.Equal(t, 400, status)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`{"name": ""}`))
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("Accept", exception.ProblemContentType)
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, 400, recorder.Code)
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "request does not match the API specification",
+		"instance": "/api/categories",
+		"errors": [{"field": "name", "rule": "minLength", "param": "1", "message": "must be at least 1 characters long"}]
+	}`, recorder.Body.String())
+
+	request = httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`name=Gadget`))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, 400, recorder.Code)
+
+	// Bodies are only read up to openapi.max_body_bytes.
+	status, resBody = doJSON(handler, http.MethodPost, "/api/categories", `{"name": "`+strings.Repeat("a", config.Default().OpenAPI.MaxBodyBytes)+`"}`)
+	assert.Equal(t, 413, status)
+	assert.Equal(t, "request body is larger than 1048576 bytes", resBody["data"])
+
+	// Valid traffic reaches the handlers, and their responses, errors
+	// included, match the specification.
+	parent := createCategory(t, handler, "Electronics", nil)
+	child := createCategory(t, handler, "Phones", parent)
+	for _, path := range []string{
+		"/api/categories",
+		"/api/categories?size=1&sort=-name",
+		"/api/categories?limit=1&offset=1&name=Pho&name_match=prefix",
+		fmt.Sprintf("/api/categories/%d", child),
+		fmt.Sprintf("/api/categories/%d/children", parent),
+		fmt.Sprintf("/api/categories/%d/children", child),
+		fmt.Sprintf("/api/categories/%d/ancestors", child),
+		fmt.Sprintf("/api/categories/%d/subtree", parent),
+		"/api/categories/1000",
+	} {
+		doJSON(handler, http.MethodGet, path, "")
+	}
+	status, resBody = doJSON(handler, http.MethodGet, "/api/categories?size=1", "")
+	assert.Equal(t, 200, status)
+	cursor := resBody["meta"].(map[string]interface{})["next_cursor"].(string)
+	status, _ = doJSON(handler, http.MethodGet, "/api/categories?cursor="+cursor, "")
+	assert.Equal(t, 200, status)
+	doJSON(handler, http.MethodPut, fmt.Sprintf("/api/categories/%d", child), `{"name": "Electronics"}`)
+	doJSON(handler, http.MethodPost, fmt.Sprintf("/api/categories/%d/move", child), `{"parent_id": null}`)
+	doJSON(handler, http.MethodDelete, fmt.Sprintf("/api/categories/%d", child), "")
+	assert.Equal(t, "", logs.String())
+}
+
+func TestOpenAPIResponseValidation(t *testing.T) {
+	var logs bytes.Buffer
+	handler := setUpValidatedRouter(t, &logs, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		io.WriteString(w, `{"code": 200, "status": "OK", "data": {"id": "1", "name": "Gadget"}}`)
+	}))
+
+	status, resBody := doJSON(handler, http.MethodGet, "/api/categories/1", "")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "Gadget", resBody["data"].(map[string]interface{})["name"])
+	assert.Contains(t, logs.String(), "Response to GET /api/categories/1 with status 200 does not match the API specification")
+	assert.Contains(t, logs.String(), "data.id must be an integer")
+	assert.Contains(t, logs.String(), "data.parent_id is required")
+
+	logs.Reset()
+	doJSON(handler, http.MethodDelete, "/api/categories/1", "")
+	assert.Contains(t, logs.String(), "status is not documented")
+}
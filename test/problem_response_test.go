@@ -0,0 +1,78 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProblemValidationErrors(t *testing.T) {
+	url := fmt.Sprintf("http://%s:%d/api/categories", HOST, PORT)
+	router := setUpMemoryRouter(repository.NewCategoryRepositoryMemory())
+
+	request := httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"name": "", "parent_id": -1}`))
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("Accept", "application/problem+json")
+	request.Header.Add("X-API-KEY", "RAHASIA")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	resp := recorder.Result()
+	assert.Equal(t, 400, resp.StatusCode)
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+	resBodyByte, _ := io.ReadAll(resp.Body)
+	var resBody map[string]interface{}
+	json.Unmarshal(resBodyByte, &resBody)
+
+	assert.Equal(t, "about:blank", resBody["type"])
+	assert.Equal(t, "Bad Request", resBody["title"])
+	assert.Equal(t, 400, int(resBody["status"].(float64)))
+	assert.Equal(t, "/api/categories", resBody["instance"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"field": "name", "rule": "required", "message": "name is a required field"},
+		map[string]interface{}{"field": "parent_id", "rule": "min", "param": "1", "message": "parent_id must be 1 or greater"},
+	}, resBody["errors"])
+}
+
+func TestProblemNegotiation(t *testing.T) {
+	url := fmt.Sprintf("http://%s:%d/api/categories/1000", HOST, PORT)
+	router := setUpMemoryRouter(repository.NewCategoryRepositoryMemory())
+
+	cases := map[string]string{
+		"":                         "application/json",
+		"application/json":         "application/json",
+		"application/problem+json": "application/problem+json",
+		"application/json;q=0.5, application/problem+json": "application/problem+json",
+		"application/problem+json;q=0.2, application/json": "application/json",
+	}
+	for accept, contentType := range cases {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		request.Header.Add("Accept", accept)
+		request.Header.Add("X-API-KEY", "RAHASIA")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		resp := recorder.Result()
+		assert.Equal(t, 404, resp.StatusCode, accept)
+		assert.Equal(t, contentType, resp.Header.Get("Content-Type"), accept)
+
+		resBodyByte, _ := io.ReadAll(resp.Body)
+		var resBody map[string]interface{}
+		json.Unmarshal(resBodyByte, &resBody)
+		if contentType == "application/json" {
+			assert.Equal(t, "Not Found", resBody["status"], accept)
+		} else {
+			assert.Equal(t, "category is not found", resBody["detail"], accept)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/controller"
+	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/rtanx/golang-restful-api/ratelimit"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/rtanx/golang-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	limit := ratelimit.Every(60, time.Minute, 2)
+	ctx := context.Background()
+	now := time.Now()
+
+	result, _ := store.Take(ctx, "a", limit, now)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+	assert.Equal(t, time.Second, result.ResetAfter)
+
+	result, _ = store.Take(ctx, "a", limit, now)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, _ = store.Take(ctx, "a", limit, now.Add(500*time.Millisecond))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	result, _ = store.Take(ctx, "b", limit, now)
+	assert.True(t, result.Allowed, "buckets are per key")
+
+	result, _ = store.Take(ctx, "a", limit, now.Add(time.Second))
+	assert.True(t, result.Allowed, "one token refills per second")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	categoryRepository := repository.NewCategoryRepositoryMemory()
+	categoryService := service.NewCategoryService(categoryRepository, categoryRepository, helper.NewValidator())
+	limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), config.RateLimitConfig{
+		Enabled: true,
+		Groups: map[string]config.RateLimitGroup{
+			"read": {Requests: 1, Per: config.Duration(time.Hour), Burst: 2},
+		},
+	})
+	router := app.NewRouter(controller.NewCategoryController(categoryService, config.ConcurrencyConfig{}), limiter)
+
+	keyStore := newTestKeyStore()
+	_, other, err := auth.CreateKey(context.Background(), keyStore, "other", []string{auth.ScopeCategoriesRead}, nil)
+	assert.Nil(t, err)
+	handler := middleware.NewAuthMiddleware(router, keyStore, nil, nil)
+
+	get := func(key string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+		request.Header.Add("X-API-KEY", key)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := get("RAHASIA")
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "3600", recorder.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, 200, get("RAHASIA").Code)
+
+	recorder = get("RAHASIA")
+	assert.Equal(t, 429, recorder.Code)
+	assert.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "3600", recorder.Header().Get("Retry-After"))
+	var resBody map[string]interface{}
+	json.Unmarshal(recorder.Body.Bytes(), &resBody)
+	assert.Equal(t, 429, int(resBody["code"].(float64)))
+	assert.Equal(t, "Too Many Requests", resBody["status"])
+
+	assert.Equal(t, 200, get(other).Code, "each key has its own bucket")
+
+	// Writes are in a group without a limit.
+	request := httptest.NewRequest(http.MethodDelete, "/api/categories/1", nil)
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, 404, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimitAddresses(t *testing.T) {
+	limiter := middleware.NewRateLimiter(ratelimit.NewMemoryStore(), config.RateLimitConfig{
+		Enabled: true,
+		Groups: map[string]config.RateLimitGroup{
+			"ip": {Requests: 1, Per: config.Duration(time.Hour), Burst: 2},
+		},
+	})
+	authenticated := middleware.NewAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), newTestKeyStore(), nil, nil)
+	handler := middleware.NewChain(limiter.LimitAddresses("ip")).Then(authenticated)
+
+	get := func(key string, remoteAddr string) int {
+		request := httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+		request.Header.Add("X-API-KEY", key)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// Guessed keys are limited although they never authenticate.
+	assert.Equal(t, 401, get("guess-1", "192.0.2.1:1234"))
+	assert.Equal(t, 401, get("guess-2", "192.0.2.1:1235"))
+	assert.Equal(t, 429, get("RAHASIA", "192.0.2.1:1236"))
+	assert.Equal(t, 204, get("RAHASIA", "192.0.2.2:1234"), "each address has its own bucket")
+
+	var disabled *middleware.RateLimiter
+	recorder := httptest.NewRecorder()
+	disabled.LimitAddresses("ip")(authenticated).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/categories", nil))
+	assert.Equal(t, 401, recorder.Code, "a nil limiter limits nothing")
+}
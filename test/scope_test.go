@@ -0,0 +1,63 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/controller"
+	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/rtanx/golang-restful-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeAuthorization(t *testing.T) {
+	categoryRepository := repository.NewCategoryRepositoryMemory()
+	categoryService := service.NewCategoryService(categoryRepository, categoryRepository, helper.NewValidator())
+	router := app.NewRouter(controller.NewCategoryController(categoryService, config.ConcurrencyConfig{}), nil)
+
+	keyStore := newTestKeyStore()
+	_, readOnly, err := auth.CreateKey(context.Background(), keyStore, "read-only", []string{auth.ScopeCategoriesRead}, nil)
+	assert.Nil(t, err)
+	_, writer, err := auth.CreateKey(context.Background(), keyStore, "writer", []string{auth.ScopeCategoriesRead, auth.ScopeCategoriesWrite}, nil)
+	assert.Nil(t, err)
+
+	secret := []byte("jwt-secret")
+	verifier, err := auth.NewJWTVerifier(config.JWTConfig{Secret: string(secret)})
+	assert.Nil(t, err)
+	handler := middleware.NewAuthMiddleware(router, keyStore, verifier, nil)
+
+	deleter := signJWT(t, "HS256", "", secret, map[string]interface{}{
+		"scope": "categories:read categories:delete",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	do := func(method string, path string, body string, header string, value string) int {
+		var request *http.Request
+		if body == "" {
+			request = httptest.NewRequest(method, path, nil)
+		} else {
+			request = httptest.NewRequest(method, path, strings.NewReader(body))
+		}
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add(header, value)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, 200, do(http.MethodGet, "/api/categories", "", "X-API-KEY", readOnly))
+	assert.Equal(t, 403, do(http.MethodPost, "/api/categories", `{"name": "Gadget"}`, "X-API-KEY", readOnly))
+	assert.Equal(t, 200, do(http.MethodPost, "/api/categories", `{"name": "Gadget"}`, "X-API-KEY", writer))
+	assert.Equal(t, 403, do(http.MethodDelete, "/api/categories/1", "", "X-API-KEY", writer))
+	assert.Equal(t, 403, do(http.MethodPut, "/api/categories/1", `{"name": "Gadgets"}`, "Authorization", "Bearer "+deleter))
+	assert.Equal(t, 200, do(http.MethodDelete, "/api/categories/1", "", "Authorization", "Bearer "+deleter))
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rtanx/golang-restful-api/auth"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/rtanx/golang-restful-api/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignedRequests(t *testing.T) {
+	signatures := auth.NewHMACVerifier(config.HMACConfig{
+		Keys:    []config.HMACKey{{Id: "batch", Secret: "batch-secret", Scopes: []string{auth.ScopeCategoriesWrite}}},
+		MaxSkew: config.Duration(5 * time.Minute),
+	})
+	handler := middleware.NewAuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signer, ok := auth.SignerFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, []string{auth.ScopeCategoriesWrite}, auth.ScopesFromContext(r.Context()))
+		body, _ := io.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]string{"signer": signer.Id, "body": string(body)})
+	}), newTestKeyStore(), nil, signatures)
+
+	now := time.Now()
+	body := []byte(`{"name": "Gadget"}`)
+	nonce := 0
+	signed := func(keyId string, secret string, signedAt time.Time) *http.Request {
+		nonce++
+		request := httptest.NewRequest(http.MethodPost, "/api/categories?b=2&a=1", bytes.NewReader(body))
+		protocol.SignRequest(request, body, keyId, secret, signedAt, "nonce-"+strconv.Itoa(nonce))
+		return request
+	}
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	request := signed("batch", "batch-secret", now)
+	replay := request.Clone(request.Context())
+	replay.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorder := serve(request)
+	assert.Equal(t, 200, recorder.Code)
+	assert.JSONEq(t, `{"signer": "batch", "body": "{\"name\": \"Gadget\"}"}`, recorder.Body.String())
+
+	assert.Equal(t, 401, serve(replay).Code, "replayed nonce")
+	assert.Equal(t, 401, serve(signed("batch", "batch-secret", now.Add(-10*time.Minute))).Code, "stale")
+	assert.Equal(t, 401, serve(signed("batch", "batch-secret", now.Add(10*time.Minute))).Code, "future")
+	assert.Equal(t, 401, serve(signed("batch", "guess", now)).Code, "wrong secret")
+	assert.Equal(t, 401, serve(signed("other", "batch-secret", now)).Code, "unknown key")
+
+	tampered := signed("batch", "batch-secret", now)
+	tampered.Body = io.NopCloser(bytes.NewReader([]byte(`{"name": "Other"}`)))
+	assert.Equal(t, 401, serve(tampered).Code, "tampered body")
+
+	tampered = signed("batch", "batch-secret", now)
+	tampered.URL.RawQuery = "a=1&b=3"
+	assert.Equal(t, 401, serve(tampered).Code, "tampered query")
+
+	// The query is canonicalized, so parameter order does not matter.
+	reordered := signed("batch", "batch-secret", now)
+	reordered.URL.RawQuery = "a=1&b=2"
+	assert.Equal(t, 200, serve(reordered).Code)
+
+	// A body too large to check is not an authentication failure.
+	large := bytes.Repeat([]byte("a"), middleware.MaxSignedBodySize+1)
+	request = httptest.NewRequest(http.MethodPost, "/api/categories", bytes.NewReader(large))
+	protocol.SignRequest(request, large, "batch", "batch-secret", now, "nonce-large")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(request).Code)
+
+	// Only the successful request and the reordered one left a nonce.
+	assert.Equal(t, 2, signatures.Nonces.Len())
+}
+
+func TestNonceCacheExpiry(t *testing.T) {
+	cache := auth.NewNonceCache()
+	now := time.Now()
+
+	assert.True(t, cache.Add("a", now.Add(time.Minute), now))
+	assert.False(t, cache.Add("a", now.Add(time.Minute), now))
+	assert.True(t, cache.Add("b", now.Add(time.Minute), now.Add(2*time.Minute)))
+	assert.Equal(t, 1, cache.Len())
+}
@@ -0,0 +1,197 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rtanx/golang-restful-api/app"
+	"github.com/rtanx/golang-restful-api/config"
+	"github.com/rtanx/golang-restful-api/controller"
+	"github.com/rtanx/golang-restful-api/helper"
+	"github.com/rtanx/golang-restful-api/i18n"
+	"github.com/rtanx/golang-restful-api/middleware"
+	"github.com/rtanx/golang-restful-api/repository"
+	"github.com/rtanx/golang-restful-api/service"
+	"github.com/rtanx/golang-restful-api/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setUpTracedRouter(t *testing.T, provider trace.TracerProvider) http.Handler {
+	DB := newTestDB()
+	t.Cleanup(func() { DB.Close() })
+	truncateCategory(DB)
+
+	validate := helper.NewValidator()
+	translator, err := i18n.NewTranslator(validate)
+	helper.PanicfIfErr(err)
+	categoryService := service.NewCategoryService(repository.NewCategoryRepository(testDialect), repository.NewSQLTxManager(DB), validate)
+	categoryController := controller.NewCategoryController(service.NewTracedCategoryService(categoryService), config.ConcurrencyConfig{})
+
+	return middleware.NewChain(
+		middleware.RequestID,
+		middleware.Tracing(provider),
+		middleware.Recovery,
+		middleware.Auth(newTestKeyStore(), nil, nil),
+		middleware.Locale(translator),
+	).Then(app.NewRouter(categoryController, nil))
+}
+
+func TestTracingSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+	router := setUpTracedRouter(t, provider)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/categories", strings.NewReader(`{"name": "Gadget"}`))
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	request.Header.Add("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, 200, recorder.Code)
+
+	spans := exporter.GetSpans()
+	exporter.Reset()
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+		byName[span.Name] = span
+	}
+	root, ok := byName["POST /api/categories"]
+	assert.True(t, ok, spans.Snapshots())
+	assert.Equal(t, trace.SpanKindServer, root.SpanKind)
+	assert.Equal(t, "00f067aa0ba902b7", root.Parent.SpanID().String())
+	assert.True(t, root.Parent.IsRemote())
+	assert.Equal(t, "/api/categories", spanAttribute(root, "http.route").AsString())
+	assert.Equal(t, int64(200), spanAttribute(root, "http.response.status_code").AsInt64())
+	assert.Equal(t, recorder.Header().Get(middleware.RequestIDHeader), spanAttribute(root, "http.request_id").AsString())
+
+	assert.Equal(t, root.SpanContext.SpanID(), byName["json.decode"].Parent.SpanID())
+	serviceSpan := byName["CategoryService.Create"]
+	assert.Equal(t, root.SpanContext.SpanID(), serviceSpan.Parent.SpanID())
+	for _, name := range []string{"db.begin", "db.query", "db.commit"} {
+		span, ok := byName[name]
+		assert.True(t, ok, name)
+		assert.Equal(t, serviceSpan.SpanContext.SpanID(), span.Parent.SpanID(), name)
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind, name)
+	}
+	assert.Contains(t, spanAttribute(byName["db.query"], "db.query.text").AsString(), "FROM category")
+
+	// Errors are recorded on the spans they happen in.
+	request = httptest.NewRequest(http.MethodGet, "/api/categories/1000", nil)
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	spans = exporter.GetSpans()
+	exporter.Reset()
+	assert.NotEmpty(t, spans)
+	for _, span := range spans {
+		switch span.Name {
+		case "CategoryService.FindById":
+			assert.Equal(t, codes.Error, span.Status.Code)
+			assert.Contains(t, span.Status.Description, "not found")
+			assert.Equal(t, int64(1000), spanAttribute(span, "category.id").AsInt64())
+		case "GET /api/categories/:categoryId":
+			assert.False(t, span.Parent.IsValid())
+			assert.Equal(t, codes.Unset, span.Status.Code)
+		}
+	}
+
+	// The caller decided not to sample this trace.
+	request = httptest.NewRequest(http.MethodGet, "/api/categories", nil)
+	request.Header.Add("X-API-KEY", "RAHASIA")
+	request.Header.Add("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	assert.Empty(t, exporter.GetSpans())
+}
+
+func TestTracingInject(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	defer provider.Shutdown(context.Background())
+
+	header := http.Header{}
+	tracing.Inject(context.Background(), header)
+	assert.Empty(t, header.Get("traceparent"))
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "job")
+	defer span.End()
+	_, child := tracing.Start(ctx, "step")
+	defer child.End()
+	assert.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID())
+
+	tracing.Inject(trace.ContextWithSpan(ctx, child), header)
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", child.SpanContext().TraceID(), child.SpanContext().SpanID()), header.Get("traceparent"))
+}
+
+func TestTracingExporters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exporter, err := tracing.NewFileExporter(path)
+	assert.Nil(t, err)
+	provider, err := tracing.NewTracerProvider(exporter, "category-api", 1)
+	assert.Nil(t, err)
+	ctx, root := provider.Tracer("test").Start(context.Background(), "job")
+	_, child := tracing.Start(ctx, "step")
+	child.SetAttributes(attribute.Int("attempt", 2))
+	tracing.RecordError(child, fmt.Errorf("boom"))
+	child.End()
+	root.End()
+	assert.Nil(t, provider.Shutdown(context.Background()))
+
+	out, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	assert.Len(t, lines, 2)
+	var step struct {
+		Name   string
+		Parent struct{ SpanID string }
+		Status struct{ Description string }
+	}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &step))
+	assert.Equal(t, "step", step.Name)
+	assert.Equal(t, root.SpanContext().SpanID().String(), step.Parent.SpanID)
+	assert.Equal(t, "boom", step.Status.Description)
+	assert.Contains(t, lines[0], `"Value":"category-api"`)
+
+	var mu sync.Mutex
+	var paths []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+	}))
+	defer collector.Close()
+
+	otlpExporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(collector.URL+"/v1/traces"))
+	assert.Nil(t, err)
+	provider, err = tracing.NewTracerProvider(otlpExporter, "category-api", 1)
+	assert.Nil(t, err)
+	_, span := provider.Tracer("test").Start(context.Background(), "job")
+	span.End()
+	assert.Nil(t, provider.Shutdown(context.Background()))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/v1/traces"}, paths)
+}
+
+func spanAttribute(span tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// fileExporter closes its file once the spans written to it are flushed.
+type fileExporter struct {
+	*stdouttrace.Exporter
+	file *os.File
+}
+
+// NewFileExporter appends spans to the file at path as JSON, one span per
+// line.
+func NewFileExporter(path string) (sdktrace.SpanExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileExporter{Exporter: exporter, file: file}, nil
+}
+
+func (exporter *fileExporter) Shutdown(ctx context.Context) error {
+	err := exporter.Exporter.Shutdown(ctx)
+	closeErr := exporter.file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans started here.
+const ScopeName = "github.com/rtanx/golang-restful-api"
+
+// Propagator reads and writes the W3C Trace Context traceparent header, see
+// https://www.w3.org/TR/trace-context/.
+var Propagator = propagation.TraceContext{}
+
+// NewTracerProvider exports spans with exporter in batches. It keeps
+// sampleRatio of the traces it starts; traces continued from a caller follow
+// the caller's decision.
+func NewTracerProvider(exporter sdktrace.SpanExporter, serviceName string, sampleRatio float64) (*sdktrace.TracerProvider, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	), nil
+}
+
+// Start starts a child of the span in ctx with the provider that started
+// it. Outside a trace the span records nothing.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(ScopeName).Start(ctx, name, opts...)
+}
+
+// RecordError marks span as failed with err. A nil err is ignored.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Route names the server span of the requests handle serves after method and
+// pattern, rather than the request path.
+func Route(pattern string, handle httprouter.Handle) httprouter.Handle {
+	return func(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+		span := trace.SpanFromContext(request.Context())
+		span.SetName(request.Method + " " + pattern)
+		span.SetAttributes(semconv.HTTPRoute(pattern))
+		handle(writer, request, params)
+	}
+}
+
+// Inject sets the traceparent header of an outgoing request to the span in
+// ctx, if there is one.
+func Inject(ctx context.Context, header http.Header) {
+	Propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}